@@ -0,0 +1,285 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"strings"
+	"testing"
+)
+
+// benchmarkSizes are the numbers of taxons of the benchmarked trees
+var benchmarkSizes = []int{1000, 10000, 100000}
+
+// benchmarkFanout is the number of children of every inner node of the benchmarked trees
+const benchmarkFanout = 10
+
+// statementCounter is a gorm logger counting the statements run and the rows they
+// affected or returned
+type statementCounter struct {
+	statements int
+	rows       int64
+}
+
+func (c *statementCounter) Print(v ...interface{}) {
+	if len(v) < 6 || v[0] != "sql" {
+		return
+	}
+
+	c.statements++
+	if rows, ok := v[5].(int64); ok {
+		c.rows += rows
+	}
+}
+
+func (c *statementCounter) reset() {
+	c.statements, c.rows = 0, 0
+}
+
+// report adds the statements and rows per operation to the benchmark results
+func (c *statementCounter) report(b *testing.B) {
+	b.ReportMetric(float64(c.statements)/float64(b.N), "stmts/op")
+	b.ReportMetric(float64(c.rows)/float64(b.N), "rows/op")
+}
+
+type benchmarkTree struct {
+	size    int
+	db      *gorm.DB
+	plugin  nested.Plugin
+	counter *statementCounter
+}
+
+// benchmarkDB opens a database holding a single tree of count taxons where the taxon i,
+// counted from 0, is the parent of the taxons 10i+1 to 10i+10
+func benchmarkDB(b *testing.B, count int) *benchmarkTree {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(func() {
+		db.Close()
+		os.Remove(dbName)
+	})
+
+	counter := &statementCounter{}
+	db.SetLogger(counter)
+	db.LogMode(true)
+	db.AutoMigrate(&Taxon{})
+
+	p, err := nested.Register(db)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if err := seedTree(db, count); err != nil {
+		b.Fatal(err)
+	}
+
+	counter.reset()
+
+	return &benchmarkTree{size: count, db: db, plugin: p, counter: counter}
+}
+
+// seedTree inserts the taxons with their tree values computed upfront, as inserting
+// them one at a time would renumber the tree for each of them
+func seedTree(db *gorm.DB, count int) error {
+	type row struct {
+		parent, left, right, level int
+	}
+
+	rows := make([]row, count)
+	value := 0
+	var walk func(i, level int)
+	walk = func(i, level int) {
+		value++
+		rows[i].left, rows[i].level = value, level
+		for c := benchmarkFanout*i + 1; c <= benchmarkFanout*i+benchmarkFanout && c < count; c++ {
+			rows[c].parent = i + 1
+			walk(c, level+1)
+		}
+		value++
+		rows[i].right = value
+	}
+	walk(0, 0)
+
+	tx := db.Begin()
+	for start := 0; start < count; start += 100 {
+		var values []string
+		var args []interface{}
+		for i := start; i < start+100 && i < count; i++ {
+			r := rows[i]
+			values = append(values, "(?, ?, ?, ?, ?, ?)")
+			args = append(args, i+1, fmt.Sprintf("taxon %d", i), r.parent, r.left, r.right, r.level)
+		}
+
+		err := tx.Exec(
+			"INSERT INTO taxons (id, name, parent_id, tree_left, tree_right, tree_level) VALUES "+strings.Join(values, ", "),
+			args...,
+		).Error
+		if err != nil {
+			tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// first returns the first taxon in order, matching the optional where condition
+func (t *benchmarkTree) first(b *testing.B, order string, where ...interface{}) *Taxon {
+	taxon := &Taxon{}
+	db := t.db.Order(order)
+	if len(where) > 0 {
+		db = db.Where(where[0], where[1:]...)
+	}
+
+	if err := db.First(taxon).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	return taxon
+}
+
+// resetTimer resets the benchmark timer and the statement counts once the benchmark is set up
+func (t *benchmarkTree) resetTimer(b *testing.B) {
+	b.ResetTimer()
+	t.counter.reset()
+}
+
+// untimed runs fn without timing it nor counting its statements
+func (t *benchmarkTree) untimed(b *testing.B, fn func()) {
+	b.StopTimer()
+	statements, rows := t.counter.statements, t.counter.rows
+	fn()
+	t.counter.statements, t.counter.rows = statements, rows
+	b.StartTimer()
+}
+
+func benchmarkEachSize(b *testing.B, fn func(b *testing.B, t *benchmarkTree)) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dk", size/1000), func(b *testing.B) {
+			t := benchmarkDB(b, size)
+
+			t.resetTimer(b)
+			fn(b, t)
+			b.StopTimer()
+
+			t.counter.report(b)
+		})
+	}
+}
+
+// BenchmarkInsertAppend inserts children of the last node of the tree, which shifts
+// only its ancestors
+func BenchmarkInsertAppend(b *testing.B) {
+	benchmarkEachSize(b, func(b *testing.B, t *benchmarkTree) {
+		parent := t.first(b, "tree_left desc")
+		t.resetTimer(b)
+
+		for i := 0; i < b.N; i++ {
+			if err := t.db.Create(&Taxon{Name: "new", Parent: parent}).Error; err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkInsertFirstChild inserts children of the first leaf of the tree, which shifts
+// the whole tree
+func BenchmarkInsertFirstChild(b *testing.B) {
+	benchmarkEachSize(b, func(b *testing.B, t *benchmarkTree) {
+		parent := t.first(b, "tree_left", "tree_right = tree_left + 1")
+		t.resetTimer(b)
+
+		for i := 0; i < b.N; i++ {
+			if err := t.db.Create(&Taxon{Name: "new", Parent: parent}).Error; err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMoveAcrossTree moves the first leaf of the tree under the last node and back.
+// The rows loaded before the loop are outdated by the moves, which Move reloads.
+func BenchmarkMoveAcrossTree(b *testing.B) {
+	benchmarkEachSize(b, func(b *testing.B, t *benchmarkTree) {
+		leaf := t.first(b, "tree_left", "tree_right = tree_left + 1")
+		from := t.first(b, "id", "id = ?", leaf.ParentID)
+		to := t.first(b, "tree_left desc", "id <> ?", leaf.ID)
+		t.resetTimer(b)
+
+		for i := 0; i < b.N; i++ {
+			parent := to
+			if i%2 == 1 {
+				parent = from
+			}
+
+			if err := t.plugin.Move(leaf, parent); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		t.untimed(b, func() {
+			problems, err := t.plugin.Verify(&Taxon{})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			if len(problems) > 0 {
+				b.Fatalf("the moves broke the tree: %s", problems[0].Message)
+			}
+		})
+	})
+}
+
+// BenchmarkDeleteSubtree deletes the parents of leaves from the left of the tree with
+// their children, seeding the tree again once they are all deleted
+func BenchmarkDeleteSubtree(b *testing.B) {
+	benchmarkEachSize(b, func(b *testing.B, t *benchmarkTree) {
+		level := t.first(b, "tree_level desc").TreeLevel - 1
+		t.resetTimer(b)
+
+		for i := 0; i < b.N; i++ {
+			subtree := &Taxon{}
+			t.untimed(b, func() {
+				err := t.db.Where("tree_level = ?", level).Order("tree_left").First(subtree).Error
+				if gorm.IsRecordNotFoundError(err) {
+					if err = t.db.Exec("DELETE FROM taxons").Error; err == nil {
+						err = seedTree(t.db, t.size)
+					}
+
+					if err == nil {
+						err = t.db.Where("tree_level = ?", level).Order("tree_left").First(subtree).Error
+					}
+				}
+
+				if err != nil {
+					b.Fatal(err)
+				}
+			})
+
+			if err := t.db.Delete(subtree).Error; err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkDescendants queries the descendants of the first child of the root, a tenth
+// of the tree
+func BenchmarkDescendants(b *testing.B) {
+	benchmarkEachSize(b, func(b *testing.B, t *benchmarkTree) {
+		node := t.first(b, "tree_left", "tree_level = 1")
+		t.resetTimer(b)
+
+		for i := 0; i < b.N; i++ {
+			var descendants []Taxon
+			if err := t.plugin.Descendants(node, &descendants); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -8,15 +8,42 @@ import (
 )
 
 func (p *Plugin) createCallback(scope *gorm.Scope) {
-	p.initColumnNames(scope.Value)
+	p = p.withColumns(scope.Value)
 
 	value := doubleToSingleIndirect(scope.Value)
-	if !isTreeNode(value) {
+	if isCreationIgnored(scope) || !p.isTreeNode(value) {
 		return
 	}
 
 	node := value.(Interface)
-	defer refreshNode(node, scope)
+	defer p.observeCallback(scope, "create")()
+
+	if isCanceled(scope) {
+		return
+	}
+
+	defer p.refreshNode(node, scope)
+
+	startChanges(scope)
+
+	if p.pathName != "" {
+		defer p.insertPath(node, scope)
+	}
+
+	if p.childrenCountName != "" && !isRoot(node) {
+		p.addChildrenCount(scope, node.GetParentID(), 1)
+	}
+
+	switch p.strategyOf(node) {
+	case NestedIntervals:
+		p.insertInterval(node, scope)
+
+		return
+	case ClosureTable:
+		p.insertClosure(node, scope)
+
+		return
+	}
 
 	if isRoot(node) {
 		p.updateInsertRootNode(node, scope)
@@ -30,58 +57,128 @@ func (p *Plugin) createCallback(scope *gorm.Scope) {
 	}
 }
 
+// updateCallback restructures the tree only when the parent of the node changed, saving a
+// node under its stored parent leaves it in place among its siblings
 func (p *Plugin) updateCallback(scope *gorm.Scope) {
-	p.initColumnNames(scope.Value)
+	p = p.withColumns(scope.Value)
 
 	value := doubleToSingleIndirect(scope.Value)
-	if isUpdateIgnored(scope) || !isTreeNode(value) {
+	if isUpdateIgnored(scope) || !p.isTreeNode(value) {
 		return
 	}
 
 	node := value.(Interface)
-	defer refreshNode(node, scope)
+	defer p.observeCallback(scope, "update")()
+	defer p.refreshNode(node, scope)
+
+	from := p.findCurrentParent(node, scope)
+	to, err := p.findNewParent(node, scope)
+	if err != nil {
+		scope.Err(err)
+
+		return
+	}
+
+	if isSameNode(from, to, scope) {
+		return
+	}
+
+	startChanges(scope)
 
+	if err := callBeforeMove(node, from, to); err != nil {
+		scope.Err(err)
+
+		return
+	}
+
+	if isCanceled(scope) {
+		return
+	}
+
+	switch strategy := p.strategyOf(node); {
+	case strategy == NestedIntervals:
+		p.moveInterval(node, to, scope)
+	case strategy == ClosureTable:
+		p.moveClosure(node, to, scope)
+	case to == nil:
+		p.moveToRoot(node, scope)
+	default:
+		p.moveToParent(node, to, scope)
+	}
+
+	if isCanceled(scope) {
+		return
+	}
+
+	if p.pathName != "" {
+		p.movePath(node, to, scope)
+	}
+
+	if p.childrenCountName != "" {
+		p.moveChildrenCount(from, to, scope)
+	}
+
+	if err := callAfterMove(node, from, to); err != nil {
+		scope.Err(err)
+	}
+}
+
+func (p *Plugin) moveToRoot(node Interface, scope *gorm.Scope) {
 	width := nodeWidth(node)
 	db := scope.DB().Set(settingIgnoreUpdate, true)
 
-	parent := node.GetParent()
-	if isRoot(node) {
-		max := newNodePtrFromValue(node)
-		db.Order(p.expr(":tree_right desc")).First(max)
+	max := newNodePtrFromValue(node)
+	db.Order(p.expr(":tree_right desc")).First(max)
 
-		treeOffset := (getTreeRight(max) - width) + 1 - getTreeLeft(node)
-		if treeOffset == 0 {
-			return
-		}
+	treeOffset := (getTreeRight(max) - width) + 1 - getTreeLeft(node)
+	if treeOffset == 0 {
+		return
+	}
+
+	levelOffset := 0 - getTreeLevel(node)
+	res := db.
+		Table(scope.TableName()).
+		Where(p.expr(":tree_left >= ? and :tree_right <= ?"), getTreeLeft(node), getTreeRight(node)).
+		Updates(map[string]interface{}{
+			p.treeLeftName:  gorm.Expr(p.expr("-1 * (:tree_left + ?)"), treeOffset),
+			p.treeRightName: gorm.Expr(p.expr("-1 * (:tree_right + ?)"), treeOffset),
+			p.treeLevelName: gorm.Expr(p.expr(":tree_level + ?"), levelOffset),
+		})
+	recordChange(scope, Change{
+		Kind:         ChangeMoved,
+		From:         getTreeLeft(node),
+		To:           getTreeRight(node),
+		Offset:       treeOffset,
+		LevelOffset:  levelOffset,
+		RowsAffected: res.RowsAffected,
+	})
 
-		levelOffset := 0 - getTreeLevel(node)
-		db.
-			Table(scope.TableName()).
-			Where(p.expr(":tree_left >= ? and :tree_right <= ?"), getTreeLeft(node), getTreeRight(node)).
-			Updates(map[string]interface{}{
-				p.treeLeftName:  gorm.Expr(p.expr("-1 * (:tree_left + ?)"), treeOffset),
-				p.treeRightName: gorm.Expr(p.expr("-1 * (:tree_right + ?)"), treeOffset),
-				p.treeLevelName: gorm.Expr(p.expr(":tree_level + ?"), levelOffset),
-			})
-
-		p.shiftTreeFromRightOf(scope, node, width)
-
-		db.
-			Table(scope.TableName()).
-			Where(p.expr(":tree_right < 0"), getTreeLeft(node), getTreeRight(node)).
-			Updates(map[string]interface{}{
-				p.treeLeftName:  gorm.Expr(p.expr("-1 * :tree_left")),
-				p.treeRightName: gorm.Expr(p.expr("-1 * tree_right")),
-			})
+	p.shiftTreeFromRightOf(scope, node, width)
 
-		return
+	db.
+		Table(scope.TableName()).
+		Where(p.expr(":tree_right < 0")).
+		Updates(map[string]interface{}{
+			p.treeLeftName:  gorm.Expr(p.expr("-1 * :tree_left")),
+			p.treeRightName: gorm.Expr(p.expr("-1 * :tree_right")),
+		})
+}
+
+func (p *Plugin) moveToParent(node, parent Interface, scope *gorm.Scope) {
+	width := nodeWidth(node)
+	db := scope.DB().Set(settingIgnoreUpdate, true)
+
+	// the parent right value moves to the left when the node is removed from its left side
+	parentRight := getTreeRight(parent)
+	if parentRight > getTreeRight(node) {
+		parentRight -= width
 	}
 
 	// update current node subtreee and remove it
-	treeOffset := getTreeRight(parent) - getTreeLeft(node)
+	treeOffset := parentRight - getTreeLeft(node)
 	levelOffset := getTreeLevel(parent) + 1 - getTreeLevel(node)
 
-	db.
+	res := db.
 		Table(scope.TableName()).
 		Where(p.expr(":tree_left >= ? and :tree_right <= ?"), getTreeLeft(node), getTreeRight(node)).
 		Updates(map[string]interface{}{
@@ -89,6 +186,14 @@ func (p *Plugin) updateCallback(scope *gorm.Scope) {
 			p.treeRightName: gorm.Expr(p.expr("0 - (:tree_right + ?)"), treeOffset),
 			p.treeLevelName: gorm.Expr(p.expr(":tree_level + ?"), levelOffset),
 		})
+	recordChange(scope, Change{
+		Kind:         ChangeMoved,
+		From:         getTreeLeft(node),
+		To:           getTreeRight(node),
+		Offset:       treeOffset,
+		LevelOffset:  levelOffset,
+		RowsAffected: res.RowsAffected,
+	})
 
 	// shift nodes from the right of moving node to the left
 	p.shiftTreeFromRightOf(scope, node, width)
@@ -102,6 +207,13 @@ func (p *Plugin) updateCallback(scope *gorm.Scope) {
 	updateCurrentNode(parent, map[string]interface{}{
 		p.treeRightName: getTreeRight(parent) + width,
 	}, scope)
+	recordChange(scope, Change{
+		Kind:         ChangeShifted,
+		From:         getTreeRight(parent),
+		To:           getTreeRight(parent),
+		Offset:       width,
+		RowsAffected: 1,
+	})
 
 	// put back current tree
 	db.
@@ -114,26 +226,64 @@ func (p *Plugin) updateCallback(scope *gorm.Scope) {
 }
 
 func (p *Plugin) deleteCallback(scope *gorm.Scope) {
-	p.initColumnNames(scope.Value)
+	p = p.withColumns(scope.Value)
 
 	value := doubleToSingleIndirect(scope.Value)
-	if isDeletionIgnored(scope) || !isTreeNode(scope.Value) {
+	if isDeletionIgnored(scope) || !p.isTreeNode(scope.Value) {
 		return
 	}
 
 	node := value.(Interface)
+	defer p.observeCallback(scope, "delete")()
+	defer p.refreshNode(node, scope)
+
+	startChanges(scope)
+
+	ids := p.subtreeIDs(node, scope)
+	if err := callBeforeSubtreeDelete(node, ids); err != nil {
+		scope.Err(err)
+
+		return
+	}
 
-	defer refreshNode(node, scope)
+	if isCanceled(scope) {
+		return
+	}
 
 	p.deleteTree(node, scope)
-	width := nodeWidth(node)
-	p.shiftTreeFromRightOf(scope, node, width)
+	if p.childrenCountName != "" && !isRoot(node) {
+		p.addChildrenCount(scope, node.GetParentID(), -1)
+	}
+
+	strategy := p.strategyOf(node)
+	if strategy == ClosureTable {
+		p.deleteClosure(ids, scope)
+	}
+	if strategy == NestedSet {
+		recordChange(scope, Change{
+			Kind:         ChangeDeleted,
+			From:         getTreeLeft(node),
+			To:           getTreeRight(node),
+			RowsAffected: int64(len(ids)),
+		})
+	} else {
+		recordChange(scope, Change{Kind: ChangeDeleted, RowsAffected: int64(len(ids))})
+	}
+
+	// with gap numbering or the other strategies the values of the deleted subtree are left free
+	if strategy == NestedSet && p.gap == 0 && !isCanceled(scope) {
+		p.shiftTreeFromRightOf(scope, node, nodeWidth(node))
+	}
+
+	if err := callAfterSubtreeDelete(node, ids); err != nil {
+		scope.Err(err)
+	}
 }
 
 func (p *Plugin) shiftTreeFromRightOf(scope *gorm.Scope, node Interface, offset int) {
 	db := scope.DB().Set(settingIgnoreUpdate, true)
 	treeRight := getTreeRight(node)
-	db.
+	res := db.
 		Table(scope.TableName()).
 		Where(p.expr(":tree_right > ?"), treeRight).
 		Update(p.treeRightName, gorm.Expr(p.expr(":tree_right - ?"), offset))
@@ -141,6 +291,14 @@ func (p *Plugin) shiftTreeFromRightOf(scope *gorm.Scope, node Interface, offset
 		Table(scope.TableName()).
 		Where(p.expr(":tree_left > ?"), treeRight).
 		Update(p.treeLeftName, gorm.Expr(p.expr(":tree_left - ?"), offset))
+
+	// every node with the left value shifted has the right value shifted as well
+	recordChange(scope, Change{
+		Kind:         ChangeShifted,
+		From:         treeRight + 1,
+		Offset:       -offset,
+		RowsAffected: res.RowsAffected,
+	})
 }
 
 func findParent(node Interface, scope *gorm.Scope) (Interface, bool) {
@@ -152,14 +310,99 @@ func findParent(node Interface, scope *gorm.Scope) (Interface, bool) {
 	return parent, !isZeroValue(node.GetParentID())
 }
 
+// findCurrentParent finds the parent of the node as it is stored in the tree,
+// before the node is moved.
+func (p *Plugin) findCurrentParent(node Interface, scope *gorm.Scope) Interface {
+	if p.strategyOf(node) == ClosureTable {
+		return p.findClosureParent(node, scope)
+	}
+
+	if getTreeLevel(node) == 0 {
+		return nil
+	}
+
+	where, args := p.ancestorsCondition(node)
+	parent := newNodePtrFromValue(node)
+	notFound := scope.NewDB().
+		Where(where, args...).
+		Where(p.expr(":tree_level = ?"), getTreeLevel(node)-1).
+		First(parent).
+		RecordNotFound()
+	if notFound {
+		return nil
+	}
+
+	return parent
+}
+
+// findNewParent returns the parent the node is being moved to or nil if the
+// node becomes a root. It fails when no row has the parent id of the node.
+func (p *Plugin) findNewParent(node Interface, scope *gorm.Scope) (Interface, error) {
+	if isRoot(node) {
+		return nil, nil
+	}
+
+	parent := node.GetParent()
+	if !isNilInterface(parent) {
+		return parent, nil
+	}
+
+	parent, ok := findParent(node, scope)
+	if !ok || scope.New(parent).PrimaryKeyZero() {
+		return nil, fmt.Errorf("parent not found: %v", node.GetParentID())
+	}
+
+	return parent, nil
+}
+
+func isSameNode(a, b Interface, scope *gorm.Scope) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return fmt.Sprint(scope.New(a).PrimaryKeyValue()) == fmt.Sprint(scope.New(b).PrimaryKeyValue())
+}
+
+// subtreeIDs returns the primary key of the node followed by the primary keys
+// of its descendants.
+func (p *Plugin) subtreeIDs(node Interface, scope *gorm.Scope) []interface{} {
+	ids := []interface{}{scope.New(node).PrimaryKeyValue()}
+
+	where, args := p.descendantsCondition(node)
+	db := scope.NewDB().
+		Table(scope.TableName()).
+		Select(scope.PrimaryKey()).
+		Where(where, args...)
+	if p.treeLevelName != "" {
+		db = db.Order(p.expr(":tree_level"))
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		scope.Err(err)
+
+		return ids
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id interface{}
+		if err := rows.Scan(&id); err != nil {
+			scope.Err(err)
+
+			return ids
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
 func (p *Plugin) deleteTree(node Interface, scope *gorm.Scope) {
+	where, args := p.descendantsCondition(node)
 	db := scope.DB().Set(settingIgnoreDelete, true)
-	db.Delete(
-		newNodePtrFromValue(scope.Value),
-		p.expr(":tree_left > ? AND :tree_left < ?"),
-		getTreeLeft(node),
-		getTreeRight(node),
-	)
+	db.Delete(newNodePtrFromValue(scope.Value), append([]interface{}{where}, args...)...)
 }
 
 func nodeWidth(node Interface) int {
@@ -175,10 +418,23 @@ func (p *Plugin) updateInsertRootNode(node Interface, scope *gorm.Scope) {
 	max := newNodePtrFromValue(node)
 	db.Order(p.expr(":tree_right desc")).First(max)
 	treeRight := getTreeRight(max)
+
+	// with gap numbering the root leaves room for its children
+	span := 2
+	if p.gap > 0 {
+		span = p.gap
+	}
+
 	updateCurrentNode(node, map[string]interface{}{
 		p.treeLeftName:  treeRight + 1,
-		p.treeRightName: treeRight + 2,
+		p.treeRightName: treeRight + span,
 	}, scope)
+	recordChange(scope, Change{
+		Kind:         ChangeInserted,
+		From:         treeRight + 1,
+		To:           treeRight + span,
+		RowsAffected: 1,
+	})
 }
 
 func (p *Plugin) updateTreeAfterInsertChildNode(node Interface, scope *gorm.Scope) error {
@@ -187,58 +443,82 @@ func (p *Plugin) updateTreeAfterInsertChildNode(node Interface, scope *gorm.Scop
 		panic(fmt.Errorf("parent not found: %s", node.GetParentID()))
 	}
 
-	db := scope.DB().Set(settingIgnoreUpdate, true)
-	treeRight := getTreeRight(parent)
-	db.
-		Table(scope.TableName()).
-		Where(p.expr(":tree_right >= ?"), treeRight).
-		Update(p.treeRightName, gorm.Expr(p.expr(":tree_right + 2")))
-	db.
-		Table(scope.TableName()).
-		Where(p.expr(":tree_left >= ?"), treeRight).
-		Update(p.treeLeftName, gorm.Expr(p.expr(":tree_left + 2")))
+	left, right := getTreeRight(parent), getTreeRight(parent)+1
+	if p.gap > 0 {
+		left, right = p.allocateInGap(parent, scope)
+	} else {
+		p.openGap(scope, left, 2)
+	}
 
 	updateCurrentNode(node, map[string]interface{}{
-		p.treeLeftName:  treeRight,
-		p.treeRightName: treeRight + 1,
+		p.treeLeftName:  left,
+		p.treeRightName: right,
 		p.treeLevelName: getTreeLevel(parent) + 1,
 	}, scope)
+	recordChange(scope, Change{
+		Kind:         ChangeInserted,
+		From:         left,
+		To:           right,
+		RowsAffected: 1,
+	})
 
 	return nil
 }
 
+// openGap shifts every left/right value greater or equal than treeValue by offset
+func (p *Plugin) openGap(scope *gorm.Scope, treeValue int, offset int) {
+	db := scope.DB().Set(settingIgnoreUpdate, true)
+	res := db.
+		Table(scope.TableName()).
+		Where(p.expr(":tree_right >= ?"), treeValue).
+		Update(p.treeRightName, gorm.Expr(p.expr(":tree_right + ?"), offset))
+	db.
+		Table(scope.TableName()).
+		Where(p.expr(":tree_left >= ?"), treeValue).
+		Update(p.treeLeftName, gorm.Expr(p.expr(":tree_left + ?"), offset))
+	recordChange(scope, Change{
+		Kind:         ChangeShifted,
+		From:         treeValue,
+		Offset:       offset,
+		RowsAffected: res.RowsAffected,
+	})
+}
+
 func (p *Plugin) expr(expr string) string {
 	expr = strings.Replace(expr, ":tree_left", p.treeLeftName, -1)
 	expr = strings.Replace(expr, ":tree_right", p.treeRightName, -1)
 	expr = strings.Replace(expr, ":tree_level", p.treeLevelName, -1)
+	expr = strings.Replace(expr, ":left_num", p.leftNumName, -1)
+	expr = strings.Replace(expr, ":left_den", p.leftDenName, -1)
+	expr = strings.Replace(expr, ":right_num", p.rightNumName, -1)
+	expr = strings.Replace(expr, ":right_den", p.rightDenName, -1)
+	expr = strings.Replace(expr, ":path", p.pathName, -1)
+	expr = strings.Replace(expr, ":children_count", p.childrenCountName, -1)
 
 	return expr
 }
 
-func (p *Plugin) initColumnNames(node interface{}) {
-	var dbf *gorm.Field
-
-	if p.treeRightName != "" || p.treeLeftName != "" || p.treeLevelName != "" {
-		return
-	}
-
-	if f, ok := getFieldByTagValue(node, "left"); ok {
-		if dbf, ok = p.db.NewScope(node).FieldByName(f.Name); ok {
-			p.treeLeftName = dbf.DBName
-		}
+// withColumns returns a copy of the plugin holding the column names of the type of node,
+// so that the plugin shared by the callbacks and the helpers is never mutated
+func (p *Plugin) withColumns(node interface{}) *Plugin {
+	v := reflect.Indirect(reflect.Indirect(reflect.ValueOf(node)))
+	if v.Kind() != reflect.Struct {
+		return p
 	}
 
-	if f, ok := getFieldByTagValue(node, "right"); ok {
-		if dbf, ok = p.db.NewScope(node).FieldByName(f.Name); ok {
-			p.treeRightName = dbf.DBName
-		}
-	}
-
-	if f, ok := getFieldByTagValue(node, "level"); ok {
-		if dbf, ok = p.db.NewScope(node).FieldByName(f.Name); ok {
-			p.treeLevelName = dbf.DBName
-		}
-	}
+	m := metaOf(node)
+	c := *p
+	c.treeLeftName = m.column("left")
+	c.treeRightName = m.column("right")
+	c.treeLevelName = m.column("level")
+	c.leftNumName = m.column("left_num")
+	c.leftDenName = m.column("left_den")
+	c.rightNumName = m.column("right_num")
+	c.rightDenName = m.column("right_den")
+	c.pathName = m.column("path")
+	c.childrenCountName = m.column("children_count")
+
+	return &c
 }
 
 func updateCurrentNode(node Interface, updates map[string]interface{}, scope *gorm.Scope) {
@@ -250,7 +530,7 @@ func updateCurrentNode(node Interface, updates map[string]interface{}, scope *go
 		Updates(updates)
 }
 
-func refreshNode(node Interface, scope *gorm.Scope) {
+func reloadNode(node Interface, scope *gorm.Scope) {
 	parent := node.GetParent()
 	for !isNilInterface(parent) {
 		scope.DB().First(parent)
@@ -260,79 +540,71 @@ func refreshNode(node Interface, scope *gorm.Scope) {
 	scope.DB().First(node)
 }
 
-func getFieldByTagValue(node interface{}, tagValue string) (*reflect.StructField, bool) {
-	v := reflect.Indirect(reflect.Indirect(reflect.ValueOf(node))).Interface()
-	t := reflect.TypeOf(v)
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		tv, ok := f.Tag.Lookup(tagName)
-		if !ok || tv != tagValue {
-			continue
-		}
-
-		return &f, true
-	}
-
-	return nil, false
-}
-
 func getTreeLeft(node Interface) int {
-	f, ok := getFieldByTagValue(node, "left")
-	if !ok {
-		return 0
-	}
-
-	v := reflect.Indirect(reflect.ValueOf(node))
-	return int(v.FieldByName(f.Name).Int())
+	return int(getTagInt(node, "left"))
 }
 
 func getTreeRight(node Interface) int {
-	f, ok := getFieldByTagValue(node, "right")
-	if !ok {
-		return 0
-	}
-
-	v := reflect.Indirect(reflect.ValueOf(node))
-	return int(v.FieldByName(f.Name).Int())
+	return int(getTagInt(node, "right"))
 }
 
 func getTreeLevel(node Interface) int {
-	f, ok := getFieldByTagValue(node, "level")
+	return int(getTagInt(node, "level"))
+}
+
+func getTagInt(node Interface, tagValue string) int64 {
+	v, ok := metaOf(node).value(node, tagValue)
 	if !ok {
 		return 0
 	}
 
-	v := reflect.Indirect(reflect.ValueOf(node))
-	return int(v.FieldByName(f.Name).Int())
+	return v.Int()
 }
 
-func isTreeNode(v interface{}) bool {
+func (p *Plugin) isTreeNode(v interface{}) bool {
 	node, ok := v.(Interface)
 	if !ok {
 		return false
 	}
 
+	switch p.strategyOf(node) {
+	case NestedIntervals:
+		return hasTags(node, "left_num", "left_den", "right_num", "right_den", "level")
+	case ClosureTable:
+		return true
+	}
+
 	return isValidNode(node)
 }
 
 func isValidNode(node Interface) bool {
-	_, ok := getFieldByTagValue(node, "left")
-	if !ok {
-		return false
+	return hasTags(node, "left", "right", "level")
+}
+
+func hasTags(node Interface, tagValues ...string) bool {
+	m := metaOf(node)
+	for _, tv := range tagValues {
+		if _, ok := m.field(tv); !ok {
+			return false
+		}
 	}
 
-	_, ok = getFieldByTagValue(node, "right")
+	return true
+}
+
+func isUpdateIgnored(scope *gorm.Scope) bool {
+	v, ok := scope.Get(settingIgnoreUpdate)
 	if !ok {
 		return false
 	}
 
-	_, ok = getFieldByTagValue(node, "level")
+	vv, _ := v.(bool)
 
-	return ok
+	return vv
 }
 
-func isUpdateIgnored(scope *gorm.Scope) bool {
-	v, ok := scope.Get(settingIgnoreUpdate)
+func isCreationIgnored(scope *gorm.Scope) bool {
+	v, ok := scope.Get(settingIgnoreCreate)
 	if !ok {
 		return false
 	}
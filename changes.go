@@ -0,0 +1,134 @@
+package nested
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// ChangeKind tells what happened to the nodes inside a Change window
+type ChangeKind string
+
+const (
+	// ChangeInserted a new node was inserted inside the window
+	ChangeInserted ChangeKind = "inserted"
+	// ChangeShifted the left/right values inside the window were shifted by Offset
+	ChangeShifted ChangeKind = "shifted"
+	// ChangeMoved the subtree inside the window was moved by Offset. The moved subtree
+	// is not affected by the changes that follow it in the same change set.
+	ChangeMoved ChangeKind = "moved"
+	// ChangeDeleted the nodes inside the window were deleted
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is a window of left/right values touched by a single statement. The window
+// is expressed in the values the tree had when the statement was executed. To is 0
+// when the window has no upper bound. When a statement touches several windows, the
+// rows it updated are counted by the first one.
+type Change struct {
+	Kind         ChangeKind
+	From         int
+	To           int
+	Offset       int
+	LevelOffset  int
+	RowsAffected int64
+}
+
+// ChangeSet lists, in the order they were applied, the changes made to the tree
+// by a single create, update or delete, or by a helper reordering the tree such as
+// SwapSiblings
+type ChangeSet struct {
+	Table   string
+	Changes []Change
+}
+
+// ChangeListener is notified about every change set committed by the plugin.
+// When the statement runs in a transaction begun by the caller, the listener is notified
+// once the statement is done, before that transaction is committed.
+type ChangeListener func(ChangeSet)
+
+// WithChangeListener registers a listener notified after every structural change
+func WithChangeListener(listener ChangeListener) Option {
+	return func(p *Plugin) {
+		p.changeListeners = append(p.changeListeners, listener)
+	}
+}
+
+// Changes returns the change set recorded on the db returned by Create, Save, Update or
+// Delete. The change sets of the helpers are only published to the listeners.
+func Changes(db *gorm.DB) (ChangeSet, bool) {
+	v, ok := db.Get(settingChanges)
+	if !ok {
+		return ChangeSet{}, false
+	}
+
+	cs, ok := v.(*ChangeSet)
+	if !ok {
+		return ChangeSet{}, false
+	}
+
+	return *cs, true
+}
+
+// startChanges starts the change set of the statement of scope, published once the
+// statement is committed, unless the statement is run by a helper recording its own
+func startChanges(scope *gorm.Scope) {
+	if _, ok := scope.Get(settingSharedChanges); ok {
+		return
+	}
+
+	scope.Set(settingChanges, &ChangeSet{Table: scope.TableName()})
+	scope.Set(settingChangesScope, scope)
+}
+
+func recordChange(scope *gorm.Scope, change Change) {
+	v, ok := scope.Get(settingChanges)
+	if !ok {
+		return
+	}
+
+	cs, ok := v.(*ChangeSet)
+	if !ok {
+		return
+	}
+
+	cs.Changes = append(cs.Changes, change)
+}
+
+// publishCallback notifies the listeners about the change set started by the statement
+// of scope, rather than by the statements it ran, after its transaction is committed
+func (p *Plugin) publishCallback(scope *gorm.Scope) {
+	if v, ok := scope.Get(settingChangesScope); !ok || v != scope || scope.HasError() {
+		return
+	}
+
+	if cs, ok := Changes(scope.DB()); ok {
+		p.publishChanges(cs)
+	}
+}
+
+func (p *Plugin) publishChanges(cs ChangeSet) {
+	if len(cs.Changes) == 0 {
+		return
+	}
+
+	for _, listener := range p.changeListeners {
+		listener(cs)
+	}
+}
+
+// withChanges runs fn with a copy of the plugin recording the changes of the statements
+// it runs in a single change set on the table of node, published once fn succeeded. When
+// the plugin already records a change set, fn records in that one.
+func (p *Plugin) withChanges(node Interface, fn func(p *Plugin) error) error {
+	if _, ok := p.db.Get(settingSharedChanges); ok {
+		return fn(p)
+	}
+
+	cs := &ChangeSet{Table: p.db.NewScope(node).TableName()}
+	if err := fn(p.withDB(p.db.Set(settingChanges, cs).Set(settingSharedChanges, true))); err != nil {
+		return err
+	}
+
+	p.publishChanges(*cs)
+
+	return nil
+}
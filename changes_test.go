@@ -0,0 +1,155 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type ChangesTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	plugin    nested.Plugin
+	published []nested.ChangeSet
+	onPublish func(nested.ChangeSet)
+}
+
+func (suite *ChangesTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Taxon{})
+	suite.published = nil
+	suite.onPublish = nil
+
+	suite.plugin, err = nested.Register(suite.db, nested.WithChangeListener(func(cs nested.ChangeSet) {
+		suite.published = append(suite.published, cs)
+		if suite.onPublish != nil {
+			suite.onPublish(cs)
+		}
+	}))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *ChangesTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *ChangesTestSuite) TestInsertChanges() {
+	electronics := Taxon{Name: "Electronics"}
+	cs, ok := nested.Changes(suite.db.Create(&electronics))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), "taxons", cs.Table)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeInserted, From: 1, To: 2, RowsAffected: 1},
+	}, cs.Changes)
+
+	television := Taxon{Name: "Television", Parent: &electronics}
+	cs, ok = nested.Changes(suite.db.Create(&television))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeShifted, From: 2, Offset: 2, RowsAffected: 1},
+		{Kind: nested.ChangeInserted, From: 2, To: 3, RowsAffected: 1},
+	}, cs.Changes)
+
+	assert.Len(suite.T(), suite.published, 2)
+	assert.Equal(suite.T(), cs, suite.published[1])
+}
+
+func (suite *ChangesTestSuite) TestMoveAndDeleteChanges() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	tube := Taxon{Name: "Tube", Parent: &television}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&tube)
+	suite.db.Save(&radio)
+	suite.published = nil
+
+	radio.Parent = &television
+	radio.ParentID = television.ID
+	cs, ok := nested.Changes(suite.db.Save(&radio))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeMoved, From: 6, To: 7, Offset: -1, LevelOffset: 1, RowsAffected: 1},
+		{Kind: nested.ChangeShifted, From: 8, Offset: -2, RowsAffected: 1},
+		{Kind: nested.ChangeShifted, From: 6, Offset: 2, RowsAffected: 1},
+		{Kind: nested.ChangeShifted, From: 5, To: 5, Offset: 2, RowsAffected: 1},
+	}, cs.Changes)
+
+	cs, ok = nested.Changes(suite.db.Delete(&television))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeDeleted, From: 2, To: 7, RowsAffected: 3},
+		{Kind: nested.ChangeShifted, From: 8, Offset: -6, RowsAffected: 1},
+	}, cs.Changes)
+
+	assert.Len(suite.T(), suite.published, 2)
+}
+
+func (suite *ChangesTestSuite) TestNoChangesWithoutMove() {
+	electronics := Taxon{Name: "Electronics"}
+	suite.db.Create(&electronics)
+	suite.published = nil
+
+	electronics.Name = "Gadgets"
+	_, ok := nested.Changes(suite.db.Save(&electronics))
+	assert.False(suite.T(), ok)
+	assert.Empty(suite.T(), suite.published)
+}
+
+func (suite *ChangesTestSuite) TestPublishedAfterCommit() {
+	// the listener reads the table with another connection, which only sees committed rows
+	var counts []int
+	suite.onPublish = func(cs nested.ChangeSet) {
+		var count int
+		assert.NoError(suite.T(), suite.db.Model(&Taxon{}).Count(&count).Error)
+		counts = append(counts, count)
+	}
+
+	electronics := Taxon{Name: "Electronics"}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Create(&electronics)
+	suite.db.Create(&radio)
+	assert.Equal(suite.T(), []int{1, 2}, counts)
+
+	// Move runs the update in its own transaction
+	var parentIDs []uint
+	suite.onPublish = func(cs nested.ChangeSet) {
+		var stored Taxon
+		assert.NoError(suite.T(), suite.db.First(&stored, radio.ID).Error)
+		parentIDs = append(parentIDs, stored.ParentID)
+	}
+	assert.NoError(suite.T(), suite.plugin.Move(&radio, nil))
+	assert.Equal(suite.T(), []uint{0}, parentIDs)
+
+	// with a transaction of the caller the listener runs before its commit
+	suite.onPublish = func(cs nested.ChangeSet) {
+		var count int
+		assert.NoError(suite.T(), suite.db.Model(&Taxon{}).Count(&count).Error)
+		counts = append(counts, count)
+	}
+	counts = nil
+	tx := suite.db.Begin()
+	assert.NoError(suite.T(), tx.Create(&Taxon{Name: "Books"}).Error)
+	assert.NoError(suite.T(), tx.Commit().Error)
+	assert.Equal(suite.T(), []int{2}, counts)
+}
+
+func TestChangesTestSuite(t *testing.T) {
+	suite.Run(t, new(ChangesTestSuite))
+}
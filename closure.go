@@ -0,0 +1,301 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"reflect"
+)
+
+const closureTableSuffix = "_closure"
+
+// AutoMigrate runs the auto migration of models, e.g. &Category{}, and creates the closure
+// tables of the ones using the closure table strategy. The closure rows of the nodes stored
+// before are backfilled by Rebuild.
+func (p *Plugin) AutoMigrate(models ...Interface) error {
+	for _, model := range models {
+		if err := p.db.AutoMigrate(model).Error; err != nil {
+			return err
+		}
+
+		if p.strategyOf(model) == ClosureTable {
+			if err := p.migrateClosure(model); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrateClosure creates the closure table of model and rebuilds the tree when a node has
+// no closure rows
+func (p *Plugin) migrateClosure(model Interface) error {
+	scope := p.db.NewScope(model)
+	name := scope.TableName() + closureTableSuffix
+	row := reflect.New(closureRowType(scope.PrimaryField().Struct.Type, name)).Interface()
+	if err := p.db.Table(name).AutoMigrate(row).Error; err != nil {
+		return fmt.Errorf("migrate %s: %s", name, err)
+	}
+
+	var missing int
+	err := p.db.
+		Table(scope.TableName()).
+		Where(fmt.Sprintf(
+			"%s NOT IN (SELECT descendant_id FROM %s WHERE depth = 0)",
+			scope.Quote(scope.PrimaryKey()),
+			scope.Quote(name),
+		)).
+		Count(&missing).
+		Error
+	if err != nil {
+		return fmt.Errorf("migrate %s: %s", name, err)
+	}
+
+	if missing == 0 {
+		return nil
+	}
+
+	if err := p.Rebuild(model); err != nil {
+		return fmt.Errorf("migrate %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// closureTable returns the quoted name of the closure table of the scope model, created
+// by AutoMigrate
+func (p *Plugin) closureTable(scope *gorm.Scope) string {
+	return scope.Quote(scope.TableName() + closureTableSuffix)
+}
+
+func closureRowType(idType reflect.Type, table string) reflect.Type {
+	index := "idx_" + table
+
+	return reflect.StructOf([]reflect.StructField{
+		{
+			Name: "AncestorID",
+			Type: idType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"column:ancestor_id;unique_index:%s"`, index)),
+		},
+		{
+			Name: "DescendantID",
+			Type: idType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"column:descendant_id;unique_index:%s;index:%s_descendant"`, index, index)),
+		},
+		{
+			Name: "Depth",
+			Type: reflect.TypeOf(0),
+			Tag:  `gorm:"column:depth"`,
+		},
+	})
+}
+
+func (p *Plugin) insertClosure(node Interface, scope *gorm.Scope) {
+	table := p.closureTable(scope)
+	id := scope.PrimaryKeyValue()
+	db := scope.NewDB()
+
+	res := exec(db, fmt.Sprintf("INSERT INTO %s (ancestor_id, descendant_id, depth) VALUES (?, ?, 0)", table), id, id)
+	if res.Error != nil {
+		scope.Err(res.Error)
+
+		return
+	}
+
+	rows := res.RowsAffected
+	level := 0
+	if !isRoot(node) {
+		parent, ok := findParent(node, scope)
+		if !ok {
+			panic(fmt.Errorf("parent not found: %v", node.GetParentID()))
+		}
+
+		res = exec(
+			db,
+			fmt.Sprintf(
+				"INSERT INTO %s (ancestor_id, descendant_id, depth) SELECT ancestor_id, ?, depth + 1 FROM %s WHERE descendant_id = ?",
+				table,
+				table,
+			),
+			id,
+			node.GetParentID(),
+		)
+		if res.Error != nil {
+			scope.Err(res.Error)
+
+			return
+		}
+
+		rows += res.RowsAffected
+		level = getTreeLevel(parent) + 1
+	}
+
+	if p.treeLevelName != "" {
+		updateCurrentNode(node, map[string]interface{}{p.treeLevelName: level}, scope)
+	}
+
+	recordChange(scope, Change{Kind: ChangeInserted, RowsAffected: rows})
+}
+
+// moveClosure detaches the node subtree from its ancestors and attaches it to parent
+func (p *Plugin) moveClosure(node, parent Interface, scope *gorm.Scope) {
+	table := p.closureTable(scope)
+	id := scope.PrimaryKeyValue()
+	db := scope.NewDB()
+
+	// the subtree is loaded first, as MySQL cannot delete from a table read by a subquery
+	subtree, err := closureSubtree(db, table, id)
+	if err != nil {
+		scope.Err(err)
+
+		return
+	}
+
+	res := exec(
+		db,
+		fmt.Sprintf("DELETE FROM %s WHERE descendant_id IN (?) AND ancestor_id NOT IN (?)", table),
+		subtree,
+		subtree,
+	)
+	if res.Error != nil {
+		scope.Err(res.Error)
+
+		return
+	}
+
+	rows := res.RowsAffected
+	level := 0
+	if parent != nil {
+		db.First(parent)
+		res = exec(
+			db,
+			fmt.Sprintf(
+				"INSERT INTO %s (ancestor_id, descendant_id, depth) "+
+					"SELECT super.ancestor_id, sub.descendant_id, super.depth + sub.depth + 1 "+
+					"FROM %s super CROSS JOIN %s sub WHERE super.descendant_id = ? AND sub.ancestor_id = ?",
+				table,
+				table,
+				table,
+			),
+			scope.New(parent).PrimaryKeyValue(),
+			id,
+		)
+		if res.Error != nil {
+			scope.Err(res.Error)
+
+			return
+		}
+
+		rows += res.RowsAffected
+		level = getTreeLevel(parent) + 1
+	}
+
+	levelOffset := level - getTreeLevel(node)
+	if p.treeLevelName != "" && levelOffset != 0 {
+		db.
+			Set(settingIgnoreUpdate, true).
+			Table(scope.TableName()).
+			Where(fmt.Sprintf("%s IN (?)", scope.Quote(scope.PrimaryKey())), subtree).
+			Update(p.treeLevelName, gorm.Expr(p.expr(":tree_level + ?"), levelOffset))
+	}
+
+	recordChange(scope, Change{Kind: ChangeMoved, LevelOffset: levelOffset, RowsAffected: rows})
+}
+
+// closureSubtree returns the ids of the node with the primary key id and of its descendants
+func closureSubtree(db *gorm.DB, table string, id interface{}) ([]interface{}, error) {
+	rows, err := db.Raw(fmt.Sprintf("SELECT descendant_id FROM %s WHERE ancestor_id = ?", table), id).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []interface{}
+	for rows.Next() {
+		var id interface{}
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// deleteClosure removes the closure rows of the node subtree made of ids
+func (p *Plugin) deleteClosure(ids []interface{}, scope *gorm.Scope) {
+	res := exec(
+		scope.NewDB(),
+		fmt.Sprintf("DELETE FROM %s WHERE descendant_id IN (?)", p.closureTable(scope)),
+		ids,
+	)
+	if res.Error != nil {
+		scope.Err(res.Error)
+	}
+}
+
+// findClosureParent finds the parent of the node as it is stored in the closure table
+func (p *Plugin) findClosureParent(node Interface, scope *gorm.Scope) Interface {
+	parent := newNodePtrFromValue(node)
+	notFound := scope.NewDB().
+		Where(
+			fmt.Sprintf(
+				"%s IN (SELECT ancestor_id FROM %s WHERE descendant_id = ? AND depth = 1)",
+				scope.Quote(scope.PrimaryKey()),
+				p.closureTable(scope),
+			),
+			scope.PrimaryKeyValue(),
+		).
+		First(parent).
+		RecordNotFound()
+	if notFound {
+		return nil
+	}
+
+	return parent
+}
+
+// closureCondition matches the nodes having the node as the other end of a closure
+// row, e.g. its descendants when column is descendant_id and other is ancestor_id
+func (p *Plugin) closureCondition(node Interface, column, other string) (string, []interface{}) {
+	scope := p.db.NewScope(node)
+
+	return fmt.Sprintf(
+		"%s IN (SELECT %s FROM %s WHERE %s = ? AND depth > 0)",
+		scope.Quote(scope.PrimaryKey()),
+		column,
+		p.closureTable(scope),
+		other,
+	), []interface{}{scope.PrimaryKeyValue()}
+}
+
+func (p *Plugin) closureDescendants(node Interface, out interface{}) error {
+	return p.closureJoin(node, "descendant_id", "ancestor_id").
+		Order("closure.depth").
+		Find(out).
+		Error
+}
+
+func (p *Plugin) closureAncestors(node Interface, out interface{}) error {
+	return p.closureJoin(node, "ancestor_id", "descendant_id").
+		Order("closure.depth desc").
+		Find(out).
+		Error
+}
+
+func (p *Plugin) closureJoin(node Interface, column, other string) *gorm.DB {
+	scope := p.db.NewScope(node)
+	table := scope.QuotedTableName()
+
+	return p.db.
+		Select(table+".*").
+		Joins(fmt.Sprintf(
+			"JOIN %s closure ON closure.%s = %s.%s",
+			p.closureTable(scope),
+			column,
+			table,
+			scope.Quote(scope.PrimaryKey()),
+		)).
+		Where(fmt.Sprintf("closure.%s = ? AND closure.depth > 0", other), scope.PrimaryKeyValue())
+}
@@ -0,0 +1,220 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"testing"
+)
+
+type Category struct {
+	ID       uint `gorm:"primary_key"`
+	Name     string
+	ParentID uint
+	Parent   *Category `gorm:"association_autoupdate:false"`
+	Level    int       `gorm-nested:"level"`
+}
+
+func (c Category) GetParentID() interface{} {
+	return c.ParentID
+}
+
+func (c Category) GetParent() nested.Interface {
+	return c.Parent
+}
+
+func (c Category) TreeStrategy() nested.Strategy {
+	return nested.ClosureTable
+}
+
+type closureRow struct {
+	AncestorID   uint
+	DescendantID uint
+	Depth        int
+}
+
+type ClosureTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *ClosureTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Category{}, &Taxon{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *ClosureTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *ClosureTestSuite) createTree() (*Category, *Category, *Category, *Category) {
+	books := Category{Name: "Books"}
+	fiction := Category{Name: "Fiction", Parent: &books}
+	fantasy := Category{Name: "Fantasy", Parent: &fiction}
+	poetry := Category{Name: "Poetry", Parent: &books}
+	suite.db.Save(&fantasy)
+	suite.db.Save(&poetry)
+
+	return &books, &fiction, &fantasy, &poetry
+}
+
+func (suite *ClosureTestSuite) closureRows() []closureRow {
+	var rows []closureRow
+	suite.db.Table("categories_closure").Order("ancestor_id, descendant_id").Find(&rows)
+
+	return rows
+}
+
+func (suite *ClosureTestSuite) names(categories []Category) []string {
+	var names []string
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+
+	return names
+}
+
+func (suite *ClosureTestSuite) TestInsert() {
+	books, fiction, fantasy, poetry := suite.createTree()
+
+	assert.Equal(suite.T(), []closureRow{
+		{books.ID, books.ID, 0},
+		{books.ID, fiction.ID, 1},
+		{books.ID, fantasy.ID, 2},
+		{books.ID, poetry.ID, 1},
+		{fiction.ID, fiction.ID, 0},
+		{fiction.ID, fantasy.ID, 1},
+		{fantasy.ID, fantasy.ID, 0},
+		{poetry.ID, poetry.ID, 0},
+	}, suite.closureRows())
+	assert.Equal(suite.T(), 2, fantasy.Level)
+}
+
+func (suite *ClosureTestSuite) TestAutoMigrate() {
+	books, fiction, fantasy, poetry := suite.createTree()
+	suite.db.DropTable("categories_closure")
+
+	// the callbacks never create the closure table
+	drama := Category{Name: "Drama", ParentID: books.ID}
+	assert.Error(suite.T(), suite.db.Save(&drama).Error)
+
+	var count int
+	suite.db.Model(&Category{}).Count(&count)
+	assert.Equal(suite.T(), 4, count)
+
+	// the closure rows of the stored nodes are backfilled
+	suite.db.Exec("UPDATE categories SET level = 0")
+	assert.NoError(suite.T(), suite.plugin.AutoMigrate(&Category{}))
+	assert.NoError(suite.T(), suite.plugin.AutoMigrate(&Category{}))
+
+	assert.Equal(suite.T(), []closureRow{
+		{books.ID, books.ID, 0},
+		{books.ID, fiction.ID, 1},
+		{books.ID, fantasy.ID, 2},
+		{books.ID, poetry.ID, 1},
+		{fiction.ID, fiction.ID, 0},
+		{fiction.ID, fantasy.ID, 1},
+		{fantasy.ID, fantasy.ID, 0},
+		{poetry.ID, poetry.ID, 0},
+	}, suite.closureRows())
+
+	suite.db.First(fantasy, fantasy.ID)
+	assert.Equal(suite.T(), 2, fantasy.Level)
+	nestedtest.AssertValid(suite.T(), suite.db, &Category{})
+}
+
+func (suite *ClosureTestSuite) TestMove() {
+	books, fiction, fantasy, poetry := suite.createTree()
+
+	assert.NoError(suite.T(), suite.plugin.Move(fiction, poetry))
+	assert.Equal(suite.T(), poetry.ID, fiction.ParentID)
+	assert.Equal(suite.T(), 2, fiction.Level)
+
+	var descendants []Category
+	assert.NoError(suite.T(), suite.plugin.Descendants(poetry, &descendants))
+	assert.Equal(suite.T(), []string{"Fiction", "Fantasy"}, suite.names(descendants))
+
+	var ancestors []Category
+	assert.NoError(suite.T(), suite.plugin.Ancestors(fantasy, &ancestors))
+	assert.Equal(suite.T(), []string{"Books", "Poetry", "Fiction"}, suite.names(ancestors))
+	assert.Equal(suite.T(), 3, ancestors[2].Level+1)
+
+	assert.Error(suite.T(), suite.plugin.Move(books, fantasy))
+
+	assert.NoError(suite.T(), suite.plugin.Move(fiction, nil))
+	assert.Equal(suite.T(), 0, fiction.Level)
+
+	ancestors = nil
+	assert.NoError(suite.T(), suite.plugin.Ancestors(fantasy, &ancestors))
+	assert.Equal(suite.T(), []string{"Fiction"}, suite.names(ancestors))
+
+	descendants = nil
+	assert.NoError(suite.T(), suite.plugin.Descendants(books, &descendants))
+	assert.Equal(suite.T(), []string{"Poetry"}, suite.names(descendants))
+}
+
+func (suite *ClosureTestSuite) TestDelete() {
+	books, fiction, _, poetry := suite.createTree()
+
+	suite.db.Delete(fiction)
+
+	var count int
+	suite.db.Model(&Category{}).Count(&count)
+	assert.Equal(suite.T(), 2, count)
+	assert.Equal(suite.T(), []closureRow{
+		{books.ID, books.ID, 0},
+		{books.ID, poetry.ID, 1},
+		{poetry.ID, poetry.ID, 0},
+	}, suite.closureRows())
+}
+
+func (suite *ClosureTestSuite) TestStrategyPerModel() {
+	suite.createTree()
+
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&television)
+	suite.db.Save(&radio)
+
+	assert.NoError(suite.T(), suite.plugin.Move(&radio, &television))
+
+	var taxons []Taxon
+	assert.NoError(suite.T(), suite.plugin.Descendants(&electronics, &taxons))
+	assert.Len(suite.T(), taxons, 2)
+	assert.Equal(suite.T(), "Television", taxons[0].Name)
+	assert.Equal(suite.T(), 2, taxons[0].TreeLeft)
+	assert.Equal(suite.T(), 5, taxons[0].TreeRight)
+	assert.Equal(suite.T(), "Radio", taxons[1].Name)
+	assert.Equal(suite.T(), 3, taxons[1].TreeLeft)
+	assert.Equal(suite.T(), 4, taxons[1].TreeRight)
+	assert.Equal(suite.T(), 2, taxons[1].TreeLevel)
+}
+
+func TestClosureTestSuite(t *testing.T) {
+	suite.Run(t, new(ClosureTestSuite))
+}
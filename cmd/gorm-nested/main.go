@@ -0,0 +1,346 @@
+// Command gorm-nested inspects and repairs the trees stored by the gorm-nested plugin.
+//
+// Usage:
+//
+//	gorm-nested -dsn shop.db -table taxons [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	verify                                    print the problems of the stored tree
+//	rebuild                                   recompute the stored tree from the parent ids
+//	print [ascii|dot]                         render the whole tree
+//	export [-fields a,b] [-depth n] ID        write the subtree of ID as JSON
+//	import [-format json|yaml] [-parent ID] [FILE]
+//	                                          insert a JSON or YAML document read from FILE or stdin
+//	move ID [PARENT]                          move ID under PARENT, or to the roots
+//
+// The database is a sqlite file, the only driver built in. The column flags name the
+// columns of the table. The nodes only hold the id, parent id, name and tree columns, so
+// export and import ignore the other columns. Each command runs in a transaction on a
+// temporary copy of these columns, written back to the table when the command succeeds.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/vcraescu/gorm-nested"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+var errUsage = errors.New("usage: gorm-nested -dsn DSN -table TABLE [flags] verify|rebuild|print|export|import|move [arguments]")
+
+// cli runs a command against the table of model
+type cli struct {
+	db       *gorm.DB
+	plugin   nested.Plugin
+	strategy nested.Strategy
+	model    nested.Interface
+	stdin    io.Reader
+	stdout   io.Writer
+
+	// columns pairs the columns of the node type with the columns of the table, id first
+	columns []column
+}
+
+// readers are the commands which leave the table alone
+var readers = map[string]bool{"verify": true, "print": true, "export": true}
+
+var commands = map[string]func(ctx context.Context, c *cli, args []string) error{
+	"verify":  verify,
+	"rebuild": rebuild,
+	"print":   printTree,
+	"export":  export,
+	"import":  importNodes,
+	"move":    move,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "gorm-nested: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("gorm-nested", flag.ContinueOnError)
+	dsn := fs.String("dsn", "", "sqlite database file")
+	table := fs.String("table", "", "table of the tree")
+	strategy := fs.String("strategy", "nested-set", "nested-set, nested-intervals or closure-table")
+	columns := map[string]*string{
+		"ID":        fs.String("id", "id", "primary key column"),
+		"ParentID":  fs.String("parent", "parent_id", "parent id column"),
+		"Name":      fs.String("name", "name", "column labelling the nodes"),
+		"TreeLeft":  fs.String("left", "tree_left", "left column of the nested set"),
+		"TreeRight": fs.String("right", "tree_right", "right column of the nested set"),
+		"TreeLevel": fs.String("level", "tree_level", "level column"),
+		"LeftNum":   fs.String("left-num", "left_num", "left numerator column of the nested intervals"),
+		"LeftDen":   fs.String("left-den", "left_den", "left denominator column of the nested intervals"),
+		"RightNum":  fs.String("right-num", "right_num", "right numerator column of the nested intervals"),
+		"RightDen":  fs.String("right-den", "right_den", "right denominator column of the nested intervals"),
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, ok := strategies[*strategy]
+	if !ok {
+		return fmt.Errorf("unknown strategy %q", *strategy)
+	}
+
+	if *dsn == "" || *table == "" || fs.NArg() == 0 {
+		return errUsage
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	db, err := gorm.Open("sqlite3", *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// gorm logs the callbacks it registers
+	db.SetLogger(gorm.Logger{LogWriter: log.New(ioutil.Discard, "", 0)})
+
+	names := map[string]string{}
+	for field, column := range columns {
+		names[field] = *column
+	}
+
+	model := newNode(s)
+	tx := db.BeginTx(ctx, nil)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.Rollback()
+
+	// the queries of the plugin on many nodes use the table of the db, the callbacks drop it
+	// and ask the node types, which read the setting
+	tx = tx.Table(*table).Set(settingTable, *table)
+
+	c := &cli{
+		db:       tx,
+		strategy: s,
+		model:    model,
+		columns:  columnsOf(tx, model, names),
+		stdin:    stdin,
+		stdout:   stdout,
+	}
+
+	if c.plugin, err = nested.Register(tx, nested.WithStrategy(s)); err != nil {
+		return err
+	}
+
+	if err := shadow(tx, c.columns); err != nil {
+		return err
+	}
+
+	if err := cmd(ctx, c, fs.Args()[1:]); err != nil || readers[fs.Arg(0)] {
+		return err
+	}
+
+	if err := unshadow(tx, c.columns); err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func verify(ctx context.Context, c *cli, args []string) error {
+	problems, err := c.plugin.VerifyContext(ctx, c.model)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range problems {
+		id := "-"
+		if p.ID != nil {
+			id = fmt.Sprint(p.ID)
+		}
+
+		fmt.Fprintf(c.stdout, "%s: %s\n", id, p.Message)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+
+	return nil
+}
+
+func rebuild(ctx context.Context, c *cli, args []string) error {
+	return c.plugin.RebuildContext(ctx, c.model)
+}
+
+func printTree(ctx context.Context, c *cli, args []string) error {
+	format := nested.ASCII
+	if len(args) > 0 {
+		format = nested.RenderFormat(args[0])
+	}
+
+	return nested.Render(c.stdout, c.db, c.model, format)
+}
+
+func export(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fields := fs.String("fields", c.column("ID")+","+c.column("Name"), "comma separated columns of the nodes")
+	depth := fs.Int("depth", 0, "levels below the node, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: export [-fields a,b] [-depth n] ID")
+	}
+
+	root, err := c.find(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// the unknown columns are left for the export to report
+	var nodeFields []string
+	names := c.names(false)
+	for _, name := range strings.Split(*fields, ",") {
+		if node, ok := names[name]; ok {
+			name = node
+		}
+
+		nodeFields = append(nodeFields, name)
+	}
+
+	var out bytes.Buffer
+	err = c.plugin.ExportJSONContext(ctx, &out, root, nested.ExportOptions{
+		Fields:   nodeFields,
+		MaxDepth: *depth,
+	})
+	if err != nil {
+		return err
+	}
+
+	return renameExportedKeys(c.stdout, out.Bytes(), c.names(true))
+}
+
+func importNodes(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "json or yaml, guessed from the file extension by default")
+	parentID := fs.String("parent", "", "parent of the imported nodes, the roots by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 {
+		return errors.New("usage: import [-format json|yaml] [-parent ID] [FILE]")
+	}
+
+	r := c.stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r = f
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(f.Name()), ".")
+		}
+	}
+
+	switch *format {
+	case "", "json":
+		*format = string(nested.JSON)
+	case "yml":
+		*format = string(nested.YAML)
+	}
+
+	parent := c.model
+	if *parentID != "" {
+		var err error
+		if parent, err = c.find(*parentID); err != nil {
+			return err
+		}
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	data = renameImportedKeys(data, c.names(false))
+
+	return c.plugin.ImportContext(ctx, bytes.NewReader(data), nested.Format(*format), parent)
+}
+
+func move(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("usage: move ID [PARENT]")
+	}
+
+	node, err := c.find(args[0])
+	if err != nil {
+		return err
+	}
+
+	var parent nested.Interface
+	if len(args) == 2 {
+		if parent, err = c.find(args[1]); err != nil {
+			return err
+		}
+	}
+
+	return c.plugin.MoveContext(ctx, node, parent)
+}
+
+// names maps the columns of the table to the columns of the node type, or the other way
+// around with toTable
+func (c *cli) names(toTable bool) map[string]string {
+	names := map[string]string{}
+	for _, col := range c.columns {
+		if toTable {
+			names[col.node] = col.table
+		} else {
+			names[col.table] = col.node
+		}
+	}
+
+	return names
+}
+
+// column returns the column of the table holding the field of the node type
+func (c *cli) column(field string) string {
+	if f, ok := c.db.NewScope(c.model).FieldByName(field); ok {
+		return c.names(true)[f.DBName]
+	}
+
+	return ""
+}
+
+// find loads the node having the id
+func (c *cli) find(id string) (nested.Interface, error) {
+	node := newNode(c.strategy)
+
+	scope := c.db.NewScope(node)
+	if err := c.db.Where(fmt.Sprintf("%s = ?", scope.Quote(scope.PrimaryKey())), id).First(node).Error; err != nil {
+		return nil, fmt.Errorf("node %s: %s", id, err)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var dbName = fmt.Sprintf("test_%d.db", rand.Int())
+
+type CLITestSuite struct {
+	suite.Suite
+}
+
+func (suite *CLITestSuite) SetupTest() {
+	err := suite.exec(`CREATE TABLE nodes (
+		node_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(255),
+		parent INTEGER,
+		lft INTEGER,
+		rgt INTEGER,
+		depth INTEGER
+	)`)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	_, err = suite.run(`[
+		{"node": {"title": "Electronics"}, "children": [
+			{"node": {"title": "Television"}, "children": [{"node": {"title": "LCD"}}]},
+			{"node": {"title": "Radio"}}
+		]},
+		{"node": {"title": "Books"}}
+	]`, "import")
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+}
+
+func (suite *CLITestSuite) TearDownTest() {
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+// run runs the command against the nodes table with stdin as input
+func (suite *CLITestSuite) run(stdin string, args ...string) (string, error) {
+	return suite.runOn("nodes", "lft", "rgt", stdin, args...)
+}
+
+// runOn runs the command against table, whose bounds are in the left and right columns
+func (suite *CLITestSuite) runOn(table, left, right, stdin string, args ...string) (string, error) {
+	flags := []string{
+		"-dsn", dbName,
+		"-table", table,
+		"-id", "node_id",
+		"-name", "title",
+		"-parent", "parent",
+		"-left", left,
+		"-right", right,
+		"-level", "depth",
+	}
+
+	var out bytes.Buffer
+	err := run(context.Background(), append(flags, args...), strings.NewReader(stdin), &out)
+
+	return out.String(), err
+}
+
+// exec runs a statement on the test database
+func (suite *CLITestSuite) exec(query string) error {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Exec(query).Error
+}
+
+func (suite *CLITestSuite) TestPrint() {
+	out, err := suite.run("", "print")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `Electronics [1,8] 0
+├── Television [2,5] 1
+│   └── LCD [3,4] 2
+└── Radio [6,7] 1
+Books [9,10] 0
+`, out)
+
+	out, err = suite.run("", "print", "dot")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), strings.HasPrefix(out, `digraph "nodes" {`))
+
+	_, err = suite.run("", "print", "svg")
+	assert.EqualError(suite.T(), err, `render: unknown format "svg"`)
+}
+
+func (suite *CLITestSuite) TestVerifyAndRebuild() {
+	out, err := suite.run("", "verify")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), out)
+
+	err = suite.exec("UPDATE nodes SET rgt = 20, depth = 5 WHERE title = 'Radio'")
+	assert.NoError(suite.T(), err)
+
+	out, err = suite.run("", "verify")
+	assert.EqualError(suite.T(), err, "3 problems found")
+	assert.Equal(suite.T(), "4: level is 5 instead of 1\n4: [6, 20] is outside of the parent bounds\n-: value 7 is missing\n", out)
+
+	_, err = suite.run("", "rebuild")
+	assert.NoError(suite.T(), err)
+
+	out, err = suite.run("", "verify")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), out)
+}
+
+func (suite *CLITestSuite) TestExportAndImport() {
+	out, err := suite.run("", "export", "-depth", "1", "2")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"node":{"node_id":2,"title":"Television"},"children":[{"node":{"node_id":3,"title":"LCD"},"children":[]}]}`, out)
+
+	file := filepath.Join(suite.T().TempDir(), "radios.yml")
+	assert.NoError(suite.T(), ioutil.WriteFile(file, []byte("- node: {title: AM}\n- node: {title: FM}\n"), 0644))
+
+	_, err = suite.run("", "import", "-parent", "4", file)
+	assert.NoError(suite.T(), err)
+
+	out, err = suite.run("", "export", "-fields", "title", "4")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"node":{"title":"Radio"},"children":[{"node":{"title":"AM"},"children":[]},{"node":{"title":"FM"},"children":[]}]}`, out)
+
+	_, err = suite.run("", "export", "9")
+	assert.EqualError(suite.T(), err, "node 9: record not found")
+}
+
+func (suite *CLITestSuite) TestMove() {
+	_, err := suite.run("", "move", "3", "5")
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.run("", "move", "4")
+	assert.NoError(suite.T(), err)
+
+	out, err := suite.run("", "print")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `Electronics [1,4] 0
+└── Television [2,3] 1
+Books [5,8] 0
+└── LCD [6,7] 1
+Radio [9,10] 0
+`, out)
+
+	_, err = suite.run("", "move")
+	assert.EqualError(suite.T(), err, "usage: move ID [PARENT]")
+}
+
+func (suite *CLITestSuite) TestColumnsOfEachRun() {
+	err := suite.exec(`CREATE TABLE menus (
+		node_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(255),
+		parent INTEGER,
+		low INTEGER,
+		high INTEGER,
+		depth INTEGER
+	)`)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.runOn("menus", "low", "high", `[{"node": {"title": "File"}, "children": [{"node": {"title": "Open"}}]}]`, "import")
+	assert.NoError(suite.T(), err)
+
+	out, err := suite.runOn("menus", "low", "high", "", "print")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "File [1,4] 0\n└── Open [2,3] 1\n", out)
+
+	out, err = suite.run("", "export", "-fields", "title,lft,rgt", "2")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"node":{"title":"Television","lft":2,"rgt":5},"children":[{"node":{"title":"LCD","lft":3,"rgt":4},"children":[]}]}`, out)
+
+	_, err = suite.runOn("menus", "low", "high", "", "verify")
+	assert.NoError(suite.T(), err)
+
+	var count int
+	db, err := gorm.Open("sqlite3", dbName)
+	assert.NoError(suite.T(), err)
+	defer db.Close()
+	assert.NoError(suite.T(), db.Table("menus").Where("low = 2 AND high = 3").Count(&count).Error)
+	assert.Equal(suite.T(), 1, count)
+}
+
+func (suite *CLITestSuite) TestUsage() {
+	var out bytes.Buffer
+	err := run(context.Background(), []string{"-dsn", dbName, "verify"}, nil, &out)
+	assert.Equal(suite.T(), errUsage, err)
+
+	_, err = suite.run("", "prune")
+	assert.EqualError(suite.T(), err, `unknown command "prune"`)
+}
+
+func TestCLITestSuite(t *testing.T) {
+	suite.Run(t, new(CLITestSuite))
+}
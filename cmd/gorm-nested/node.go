@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/vcraescu/gorm-nested"
+)
+
+// setNode is a row of a nested set table
+type setNode struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *setNode `gorm:"association_autoupdate:false"`
+	TreeLeft  int      `gorm-nested:"left"`
+	TreeRight int      `gorm-nested:"right"`
+	TreeLevel int      `gorm-nested:"level"`
+}
+
+func (n setNode) GetParentID() interface{} {
+	return n.ParentID
+}
+
+func (n setNode) GetParent() nested.Interface {
+	return n.Parent
+}
+
+func (setNode) TableName(db *gorm.DB) string {
+	return tableOf(db)
+}
+
+// intervalNode is a row of a nested intervals table
+type intervalNode struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *intervalNode `gorm:"association_autoupdate:false"`
+	LeftNum   int64         `gorm-nested:"left_num"`
+	LeftDen   int64         `gorm-nested:"left_den"`
+	RightNum  int64         `gorm-nested:"right_num"`
+	RightDen  int64         `gorm-nested:"right_den"`
+	TreeLevel int           `gorm-nested:"level"`
+}
+
+func (n intervalNode) GetParentID() interface{} {
+	return n.ParentID
+}
+
+func (n intervalNode) GetParent() nested.Interface {
+	return n.Parent
+}
+
+func (intervalNode) TableName(db *gorm.DB) string {
+	return tableOf(db)
+}
+
+// closureNode is a row of a table having a closure table
+type closureNode struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *closureNode `gorm:"association_autoupdate:false"`
+	TreeLevel int          `gorm-nested:"level"`
+}
+
+func (n closureNode) GetParentID() interface{} {
+	return n.ParentID
+}
+
+func (n closureNode) GetParent() nested.Interface {
+	return n.Parent
+}
+
+func (closureNode) TableName(db *gorm.DB) string {
+	return tableOf(db)
+}
+
+// settingTable is the db setting naming the table of the command
+const settingTable = "gorm-nested:table"
+
+// tableOf returns the table set on db, which gorm passes to the TableName of the node types
+func tableOf(db *gorm.DB) string {
+	if table, ok := db.Get(settingTable); ok {
+		return table.(string)
+	}
+
+	return ""
+}
+
+// strategies are the names accepted by -strategy
+var strategies = map[string]nested.Strategy{
+	"nested-set":       nested.NestedSet,
+	"nested-intervals": nested.NestedIntervals,
+	"closure-table":    nested.ClosureTable,
+}
+
+func newNode(strategy nested.Strategy) nested.Interface {
+	switch strategy {
+	case nested.NestedIntervals:
+		return &intervalNode{}
+	case nested.ClosureTable:
+		return &closureNode{}
+	}
+
+	return &setNode{}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/vcraescu/gorm-nested"
+	"gopkg.in/yaml.v3"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// column pairs a column of the node type with the column of the table holding its values
+type column struct {
+	node  string
+	table string
+}
+
+// columnsOf pairs the columns of model with the columns named by the flags, keyed by field
+func columnsOf(db *gorm.DB, model nested.Interface, names map[string]string) []column {
+	var columns []column
+	for _, f := range db.NewScope(model).Fields() {
+		if name, ok := names[f.Name]; ok && f.IsNormal {
+			columns = append(columns, column{node: f.DBName, table: name})
+		}
+	}
+
+	return columns
+}
+
+// shadow copies the table into a temporary table of the same name having the columns of the
+// node type. Within tx the copy hides the table, so the node types never depend on the
+// column names of a run. The id column comes first and keeps the next ids of the table.
+func shadow(tx *gorm.DB, columns []column) error {
+	scope := tx.NewScope(nil)
+	table := scope.Quote(tableOf(tx))
+
+	var defs, nodes, names []string
+	for i, c := range columns {
+		def := scope.Quote(c.node)
+		if i == 0 {
+			def += " INTEGER PRIMARY KEY"
+		}
+
+		defs = append(defs, def)
+		nodes = append(nodes, scope.Quote(c.node))
+		names = append(names, scope.Quote(c.table))
+	}
+
+	if err := tx.Exec(fmt.Sprintf("CREATE TEMP TABLE %s (%s)", table, strings.Join(defs, ", "))).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(fmt.Sprintf(
+		"INSERT INTO temp.%s (%s) SELECT %s FROM main.%s",
+		table, strings.Join(nodes, ", "), strings.Join(names, ", "), table,
+	)).Error
+}
+
+// unshadow writes the rows of the copy made by shadow back to the table, leaving the rows
+// the command did not change alone, then drops the copy
+func unshadow(tx *gorm.DB, columns []column) error {
+	scope := tx.NewScope(nil)
+	table := scope.Quote(tableOf(tx))
+
+	var nodes, names, sets, changed []string
+	for _, c := range columns {
+		name := scope.Quote(c.table)
+		nodes = append(nodes, scope.Quote(c.node))
+		names = append(names, name)
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", name, name))
+		changed = append(changed, fmt.Sprintf("%s IS NOT excluded.%s", name, name))
+	}
+
+	err := tx.Exec(fmt.Sprintf(
+		"INSERT INTO main.%s (%s) SELECT %s FROM temp.%s WHERE true ON CONFLICT (%s) DO UPDATE SET %s WHERE %s",
+		table, strings.Join(names, ", "), strings.Join(nodes, ", "), table,
+		names[0], strings.Join(sets, ", "), strings.Join(changed, " OR "),
+	)).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec(fmt.Sprintf("DROP TABLE temp.%s", table)).Error
+}
+
+// exportedNode is a node object written by ExportJSON
+type exportedNode struct {
+	Node     json.RawMessage `json:"node"`
+	Children []exportedNode  `json:"children"`
+}
+
+// rename renames the keys of the node objects of the subtree with names, keeping their order
+func (n *exportedNode) rename(names map[string]string) error {
+	dec := json.NewDecoder(bytes.NewReader(n.Node))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("{")
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+
+		key := tok.(string)
+		if name, ok := names[key]; ok {
+			key = name
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+
+		if b.Len() > 1 {
+			b.WriteString(",")
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	n.Node = b.Bytes()
+
+	for i := range n.Children {
+		if err := n.Children[i].rename(names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renameExportedKeys writes the document written by ExportJSON to w with the keys of its
+// node objects renamed
+func renameExportedKeys(w io.Writer, data []byte, names map[string]string) error {
+	var root exportedNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return err
+	}
+
+	if err := root.rename(names); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+
+	_, err := w.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+
+	return err
+}
+
+// renameImportedKeys renames the keys of the node objects of the JSON or YAML document with
+// names. The keys are replaced where they stand, so the lines of the import errors still
+// point into the document. A document which does not parse is left to the import.
+func renameImportedKeys(data []byte, names map[string]string) []byte {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return data
+	}
+
+	var keys []*yaml.Node
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, item := range n.Content {
+				walk(item)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key, value := n.Content[i], n.Content[i+1]
+				switch {
+				case key.Value == "children":
+					walk(value)
+				case key.Value == "node" && value.Kind == yaml.MappingNode:
+					for j := 0; j < len(value.Content); j += 2 {
+						if _, ok := names[value.Content[j].Value]; ok {
+							keys = append(keys, value.Content[j])
+						}
+					}
+				}
+			}
+		}
+	}
+	walk(&doc)
+
+	// replacing from the end keeps the columns of the keys before
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Line != keys[j].Line {
+			return keys[i].Line > keys[j].Line
+		}
+
+		return keys[i].Column > keys[j].Column
+	})
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for _, key := range keys {
+		line := lines[key.Line-1]
+		start := 0
+		for col := 1; col < key.Column && start < len(line); col++ {
+			_, size := utf8.DecodeRune(line[start:])
+			start += size
+		}
+
+		end, name := start+len(key.Value), names[key.Value]
+		switch key.Style {
+		case yaml.DoubleQuotedStyle:
+			end = start + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+
+			b, _ := json.Marshal(name)
+			name = string(b)
+		case yaml.SingleQuotedStyle:
+			end = start + 1
+			for end < len(line) && (line[end] != '\'' || end+1 < len(line) && line[end+1] == '\'') {
+				if line[end] == '\'' {
+					end++
+				}
+				end++
+			}
+			end++
+
+			name = "'" + strings.ReplaceAll(name, "'", "''") + "'"
+		}
+
+		if end > len(line) {
+			continue
+		}
+
+		lines[key.Line-1] = append(append(append([]byte{}, line[:start]...), name...), line[end:]...)
+	}
+
+	return bytes.Join(lines, nil)
+}
@@ -0,0 +1,176 @@
+package nested
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jinzhu/gorm"
+)
+
+const settingContext = "gorm-nested:context"
+
+// DescendantsContext is Descendants bound to ctx
+func (p *Plugin) DescendantsContext(ctx context.Context, node Interface, out interface{}) error {
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.Descendants(node, out)
+	})
+}
+
+// AncestorsContext is Ancestors bound to ctx
+func (p *Plugin) AncestorsContext(ctx context.Context, node Interface, out interface{}) error {
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.Ancestors(node, out)
+	})
+}
+
+// ChildrenContext is Children bound to ctx
+func (p *Plugin) ChildrenContext(ctx context.Context, node Interface, out interface{}) error {
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.Children(node, out)
+	})
+}
+
+// PreloadChildrenContext is PreloadChildren bound to ctx
+func (p *Plugin) PreloadChildrenContext(ctx context.Context, nodes interface{}) error {
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.PreloadChildren(nodes)
+	})
+}
+
+// LoadParentsContext is LoadParents bound to ctx
+func (p *Plugin) LoadParentsContext(ctx context.Context, nodes interface{}) error {
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.LoadParents(nodes)
+	})
+}
+
+// DescendantsCountContext is DescendantsCount bound to ctx
+func (p *Plugin) DescendantsCountContext(ctx context.Context, node Interface) (int, error) {
+	var count int
+	err := p.transaction(ctx, func(tx *Plugin) error {
+		var err error
+		count, err = tx.DescendantsCount(node)
+
+		return err
+	})
+
+	return count, err
+}
+
+// MoveContext is Move bound to ctx. The statements renumbering the tree stop as soon
+// as ctx is done and the move is rolled back.
+func (p *Plugin) MoveContext(ctx context.Context, node, parent Interface) error {
+	p, end := p.observe("move")
+	defer end()
+
+	// the changes are published once the transaction is committed
+	return p.withChanges(node, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			return tx.move(node, parent)
+		})
+	})
+}
+
+// SwapSiblingsContext is SwapSiblings bound to ctx
+func (p *Plugin) SwapSiblingsContext(ctx context.Context, a, b Interface) error {
+	// the changes are published once the transaction is committed
+	return p.withChanges(a, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			return tx.SwapSiblings(a, b)
+		})
+	})
+}
+
+// MoveUpContext is MoveUp bound to ctx
+func (p *Plugin) MoveUpContext(ctx context.Context, node Interface) (bool, error) {
+	// the changes are published once the transaction is committed
+	var moved bool
+	err := p.withChanges(node, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			var err error
+			moved, err = tx.MoveUp(node)
+
+			return err
+		})
+	})
+
+	return moved, err
+}
+
+// MoveDownContext is MoveDown bound to ctx
+func (p *Plugin) MoveDownContext(ctx context.Context, node Interface) (bool, error) {
+	// the changes are published once the transaction is committed
+	var moved bool
+	err := p.withChanges(node, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			var err error
+			moved, err = tx.MoveDown(node)
+
+			return err
+		})
+	})
+
+	return moved, err
+}
+
+// transaction runs fc with a copy of the plugin using a transaction bound to ctx, so
+// the deadline of ctx applies to every statement. The transaction is rolled back when
+// fc fails or ctx is done before the commit. When the plugin db is already a
+// transaction it is used as it is and ctx is only checked by the callbacks.
+func (p *Plugin) transaction(ctx context.Context, fc func(tx *Plugin) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := p.db.CommonDB().(*sql.Tx); ok {
+		return fc(p.withDB(p.db.Set(settingContext, ctx)))
+	}
+
+	tx := p.db.BeginTx(ctx, nil)
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = fc(p.withDB(tx.Set(settingContext, ctx)))
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+
+	panicked = false
+
+	return err
+}
+
+func (p *Plugin) withDB(db *gorm.DB) *Plugin {
+	c := *p
+	c.db = db
+
+	return &c
+}
+
+// isCanceled reports whether the context the statement runs with is done, in which case
+// the error is set on the scope so the remaining steps of the callback are skipped
+func isCanceled(scope *gorm.Scope) bool {
+	v, ok := scope.Get(settingContext)
+	if !ok {
+		return false
+	}
+
+	ctx, ok := v.(context.Context)
+	if !ok || ctx.Err() == nil {
+		return false
+	}
+
+	scope.Err(ctx.Err())
+
+	return true
+}
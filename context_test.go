@@ -0,0 +1,180 @@
+package nested_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+	"time"
+)
+
+var cancelOnMove context.CancelFunc
+
+type CancelingTaxon struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *CancelingTaxon `gorm:"association_autoupdate:false"`
+	TreeLeft  int             `gorm-nested:"left"`
+	TreeRight int             `gorm-nested:"right"`
+	TreeLevel int             `gorm-nested:"level"`
+}
+
+func (t CancelingTaxon) GetParentID() interface{} {
+	return t.ParentID
+}
+
+func (t CancelingTaxon) GetParent() nested.Interface {
+	return t.Parent
+}
+
+func (t CancelingTaxon) BeforeMove(from, to nested.Interface) error {
+	if cancelOnMove != nil {
+		cancelOnMove()
+	}
+
+	return nil
+}
+
+type ContextTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *ContextTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&CancelingTaxon{})
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *ContextTestSuite) TearDownTest() {
+	cancelOnMove = nil
+
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *ContextTestSuite) createTree() (*CancelingTaxon, *CancelingTaxon, *CancelingTaxon) {
+	electronics := CancelingTaxon{Name: "Electronics"}
+	television := CancelingTaxon{Name: "Television", Parent: &electronics}
+	radio := CancelingTaxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&television)
+	suite.db.Save(&radio)
+
+	return &electronics, &television, &radio
+}
+
+func (suite *ContextTestSuite) bounds() map[string][3]int {
+	var taxons []CancelingTaxon
+	suite.db.Find(&taxons)
+
+	bounds := map[string][3]int{}
+	for _, t := range taxons {
+		bounds[t.Name] = [3]int{t.TreeLeft, t.TreeRight, t.TreeLevel}
+	}
+
+	return bounds
+}
+
+func (suite *ContextTestSuite) TestQueries() {
+	electronics, _, _ := suite.createTree()
+
+	var taxons []CancelingTaxon
+	assert.NoError(suite.T(), suite.plugin.DescendantsContext(context.Background(), electronics, &taxons))
+	assert.Len(suite.T(), taxons, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	taxons = nil
+	assert.Equal(suite.T(), context.DeadlineExceeded, suite.plugin.ChildrenContext(ctx, electronics, &taxons))
+	assert.Empty(suite.T(), taxons)
+
+	_, err := suite.plugin.DescendantsCountContext(ctx, electronics)
+	assert.Equal(suite.T(), context.DeadlineExceeded, err)
+}
+
+func (suite *ContextTestSuite) TestMove() {
+	_, television, radio := suite.createTree()
+
+	assert.NoError(suite.T(), suite.plugin.MoveContext(context.Background(), radio, television))
+	assert.Equal(suite.T(), map[string][3]int{
+		"Electronics": {1, 6, 0},
+		"Television":  {2, 5, 1},
+		"Radio":       {3, 4, 2},
+	}, suite.bounds())
+}
+
+func (suite *ContextTestSuite) TestMoveCanceled() {
+	_, television, radio := suite.createTree()
+	before := suite.bounds()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnMove = cancel
+
+	assert.Equal(suite.T(), context.Canceled, suite.plugin.MoveContext(ctx, radio, television))
+	assert.Equal(suite.T(), before, suite.bounds())
+
+	var stored CancelingTaxon
+	suite.db.First(&stored, radio.ID)
+	assert.NotEqual(suite.T(), television.ID, stored.ParentID)
+}
+
+func (suite *ContextTestSuite) TestIndentCanceled() {
+	electronics, _, radio := suite.createTree()
+	before := suite.bounds()
+
+	// the move made by Indent runs with ctx, so it stops once ctx is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnMove = cancel
+
+	moved, err := suite.plugin.IndentContext(ctx, radio)
+	assert.Equal(suite.T(), context.Canceled, err)
+	assert.False(suite.T(), moved)
+	assert.Equal(suite.T(), before, suite.bounds())
+
+	var stored CancelingTaxon
+	suite.db.First(&stored, radio.ID)
+	assert.Equal(suite.T(), electronics.ID, stored.ParentID)
+}
+
+func (suite *ContextTestSuite) TestInTransaction() {
+	_, television, radio := suite.createTree()
+
+	tx := suite.db.Begin()
+	repo := nested.NewRepo[*CancelingTaxon](tx, suite.plugin)
+	assert.NoError(suite.T(), repo.Move(context.Background(), radio, television))
+	assert.NoError(suite.T(), tx.Rollback().Error)
+
+	assert.Equal(suite.T(), map[string][3]int{
+		"Electronics": {1, 6, 0},
+		"Television":  {2, 3, 1},
+		"Radio":       {4, 5, 1},
+	}, suite.bounds())
+}
+
+func TestContextTestSuite(t *testing.T) {
+	suite.Run(t, new(ContextTestSuite))
+}
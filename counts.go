@@ -0,0 +1,48 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+)
+
+// DescendantsCount returns the number of descendants of a loaded node. With contiguous
+// nested set numbering it is computed from the node bounds, otherwise it is counted.
+func (p *Plugin) DescendantsCount(node Interface) (int, error) {
+	p, end := p.observe("descendants_count")
+	defer end()
+
+	p = p.withColumns(node)
+
+	if p.strategyOf(node) == NestedSet && p.gap == 0 {
+		return (getTreeRight(node) - getTreeLeft(node) - 1) / 2, nil
+	}
+
+	var count int
+	where, args := p.descendantsCondition(node)
+	err := p.db.Model(newNodePtrFromValue(node)).Where(where, args...).Count(&count).Error
+
+	return count, err
+}
+
+// addChildrenCount adds delta to the children count of the node having the given id
+func (p *Plugin) addChildrenCount(scope *gorm.Scope, id interface{}, delta int) {
+	scope.DB().
+		Set(settingIgnoreUpdate, true).
+		Table(scope.TableName()).
+		Where(fmt.Sprintf("%s = ?", scope.Quote(scope.PrimaryKey())), id).
+		Update(p.childrenCountName, gorm.Expr(p.expr(":children_count + ?"), delta))
+}
+
+func (p *Plugin) moveChildrenCount(from, to Interface, scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	if from != nil {
+		p.addChildrenCount(scope, scope.New(from).PrimaryKeyValue(), -1)
+	}
+
+	if to != nil {
+		p.addChildrenCount(scope, scope.New(to).PrimaryKeyValue(), 1)
+	}
+}
@@ -0,0 +1,113 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type Folder struct {
+	ID            uint `gorm:"primary_key"`
+	Name          string
+	ParentID      uint
+	Parent        *Folder `gorm:"association_autoupdate:false"`
+	ChildrenCount int     `gorm-nested:"children_count"`
+	TreeLeft      int     `gorm-nested:"left"`
+	TreeRight     int     `gorm-nested:"right"`
+	TreeLevel     int     `gorm-nested:"level"`
+}
+
+func (f Folder) GetParentID() interface{} {
+	return f.ParentID
+}
+
+func (f Folder) GetParent() nested.Interface {
+	return f.Parent
+}
+
+type CountsTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *CountsTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Folder{})
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *CountsTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *CountsTestSuite) counts() map[string]int {
+	var folders []Folder
+	suite.db.Find(&folders)
+
+	counts := map[string]int{}
+	for _, folder := range folders {
+		counts[folder.Name] = folder.ChildrenCount
+	}
+
+	return counts
+}
+
+func (suite *CountsTestSuite) TestChildrenCount() {
+	root := Folder{Name: "Root"}
+	docs := Folder{Name: "Docs", Parent: &root}
+	music := Folder{Name: "Music", Parent: &root}
+	notes := Folder{Name: "Notes", Parent: &docs}
+	suite.db.Save(&notes)
+	suite.db.Save(&music)
+
+	assert.Equal(suite.T(), map[string]int{"Root": 2, "Docs": 1, "Music": 0, "Notes": 0}, suite.counts())
+	assert.Equal(suite.T(), 2, root.ChildrenCount)
+
+	assert.NoError(suite.T(), suite.plugin.Move(&notes, &music))
+	assert.Equal(suite.T(), map[string]int{"Root": 2, "Docs": 0, "Music": 1, "Notes": 0}, suite.counts())
+
+	assert.NoError(suite.T(), suite.plugin.Move(&music, nil))
+	assert.Equal(suite.T(), map[string]int{"Root": 1, "Docs": 0, "Music": 1, "Notes": 0}, suite.counts())
+
+	suite.db.Delete(&docs)
+	assert.Equal(suite.T(), map[string]int{"Root": 0, "Music": 1, "Notes": 0}, suite.counts())
+}
+
+func (suite *CountsTestSuite) TestDescendantsCount() {
+	root := Folder{Name: "Root"}
+	docs := Folder{Name: "Docs", Parent: &root}
+	notes := Folder{Name: "Notes", Parent: &docs}
+	suite.db.Save(&notes)
+
+	count, err := suite.plugin.DescendantsCount(&root)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, count)
+
+	count, err = suite.plugin.DescendantsCount(&notes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, count)
+}
+
+func TestCountsTestSuite(t *testing.T) {
+	suite.Run(t, new(CountsTestSuite))
+}
@@ -0,0 +1,168 @@
+package nested
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportOptions configures ExportJSON
+type ExportOptions struct {
+	// Fields are the columns written in the node object, keyed by column name. By default
+	// the whole model is written as encoded by encoding/json.
+	Fields []string
+	// MaxDepth limits the exported levels below the root, 0 meaning no limit
+	MaxDepth int
+}
+
+// exportedNode is a node of the export having its children array open
+type exportedNode struct {
+	right       int
+	hasChildren bool
+}
+
+// ExportJSON writes the subtree of root as nested {"node": ..., "children": [...]} objects.
+// The subtree is read with a single query ordered by the left values and written while
+// it is read, so only the path to the current node is kept in memory. It requires the
+// nested set strategy.
+func (p *Plugin) ExportJSON(w io.Writer, root Interface, opts ExportOptions) error {
+	p, end := p.observe("export_json")
+	defer end()
+
+	p = p.withColumns(root)
+
+	if p.strategyOf(root) != NestedSet {
+		return fmt.Errorf("export: %T does not use the nested set strategy", root)
+	}
+
+	scope := p.db.NewScope(root)
+	for _, column := range opts.Fields {
+		if _, ok := scope.FieldByName(column); !ok {
+			return fmt.Errorf("export: unknown column %s", column)
+		}
+	}
+
+	where, args := p.subtreeCondition(root)
+	db := p.db.Model(newNodePtrFromValue(root)).Where(where, args...).Order(p.expr(":tree_left"))
+	if opts.MaxDepth > 0 {
+		db = db.Where(p.expr(":tree_level <= ?"), getTreeLevel(root)+opts.MaxDepth)
+	}
+
+	if len(opts.Fields) > 0 {
+		var columns []string
+		for _, column := range append([]string{scope.PrimaryKey(), p.treeLeftName, p.treeRightName, p.treeLevelName}, opts.Fields...) {
+			columns = append(columns, scope.Quote(column))
+		}
+
+		db = db.Select(strings.Join(columns, ", "))
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var stack []*exportedNode
+	for rows.Next() {
+		node := newNodePtrFromValue(root)
+		if err := p.db.ScanRows(rows, node); err != nil {
+			return err
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].right < getTreeLeft(node) {
+			if _, err := io.WriteString(w, "]}"); err != nil {
+				return err
+			}
+
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			if parent.hasChildren {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+
+			parent.hasChildren = true
+		}
+
+		if err := p.writeExportedNode(w, node, opts.Fields); err != nil {
+			return err
+		}
+
+		stack = append(stack, &exportedNode{right: getTreeRight(node)})
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for range stack {
+		if _, err := io.WriteString(w, "]}"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportJSONContext is ExportJSON bound to ctx
+func (p *Plugin) ExportJSONContext(ctx context.Context, w io.Writer, root Interface, opts ExportOptions) error {
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.ExportJSON(w, root, opts)
+	})
+}
+
+// writeExportedNode writes the node object and opens its children array
+func (p *Plugin) writeExportedNode(w io.Writer, node Interface, fields []string) error {
+	if _, err := io.WriteString(w, `{"node":`); err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		b, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	} else {
+		values := p.db.NewScope(node)
+		for i, column := range fields {
+			f, _ := values.FieldByName(column)
+			key, err := json.Marshal(column)
+			if err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(f.Field.Interface())
+			if err != nil {
+				return err
+			}
+
+			sep := ","
+			if i == 0 {
+				sep = "{"
+			}
+
+			if _, err := fmt.Fprintf(w, "%s%s:%s", sep, key, value); err != nil {
+				return err
+			}
+		}
+
+		if _, err := io.WriteString(w, "}"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, `,"children":[`)
+
+	return err
+}
@@ -0,0 +1,130 @@
+package nested_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type ExportJSONTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *ExportJSONTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *ExportJSONTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *ExportJSONTestSuite) createTree() (*Taxon, *Taxon) {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	lcd := Taxon{Name: "LCD", Parent: &television}
+	plasma := Taxon{Name: "Plasma", Parent: &television}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	books := Taxon{Name: "Books"}
+	suite.db.Save(&lcd)
+	suite.db.Save(&plasma)
+	suite.db.Save(&radio)
+	suite.db.Save(&books)
+
+	return &electronics, &television
+}
+
+func (suite *ExportJSONTestSuite) TestFields() {
+	electronics, _ := suite.createTree()
+
+	var buf bytes.Buffer
+	err := suite.plugin.ExportJSON(&buf, electronics, nested.ExportOptions{Fields: []string{"name", "tree_level"}})
+	assert.NoError(suite.T(), err)
+	assert.Equal(
+		suite.T(),
+		`{"node":{"name":"Electronics","tree_level":0},"children":[`+
+			`{"node":{"name":"Television","tree_level":1},"children":[`+
+			`{"node":{"name":"LCD","tree_level":2},"children":[]},`+
+			`{"node":{"name":"Plasma","tree_level":2},"children":[]}]},`+
+			`{"node":{"name":"Radio","tree_level":1},"children":[]}]}`,
+		buf.String(),
+	)
+}
+
+func (suite *ExportJSONTestSuite) TestMaxDepth() {
+	electronics, _ := suite.createTree()
+
+	var buf bytes.Buffer
+	err := suite.plugin.ExportJSON(&buf, electronics, nested.ExportOptions{Fields: []string{"name"}, MaxDepth: 1})
+	assert.NoError(suite.T(), err)
+	assert.Equal(
+		suite.T(),
+		`{"node":{"name":"Electronics"},"children":[{"node":{"name":"Television"},"children":[]},{"node":{"name":"Radio"},"children":[]}]}`,
+		buf.String(),
+	)
+}
+
+func (suite *ExportJSONTestSuite) TestWholeModel() {
+	_, television := suite.createTree()
+
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), suite.plugin.ExportJSON(&buf, television, nested.ExportOptions{}))
+
+	var doc struct {
+		Node     Taxon
+		Children []struct {
+			Node Taxon
+		}
+	}
+	assert.NoError(suite.T(), json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(suite.T(), "Television", doc.Node.Name)
+	assert.Equal(suite.T(), 2, doc.Node.TreeLeft)
+	assert.Len(suite.T(), doc.Children, 2)
+	assert.Equal(suite.T(), "Plasma", doc.Children[1].Node.Name)
+	assert.Equal(suite.T(), television.ID, doc.Children[1].Node.ParentID)
+}
+
+func (suite *ExportJSONTestSuite) TestErrors() {
+	electronics, _ := suite.createTree()
+
+	var buf bytes.Buffer
+	err := suite.plugin.ExportJSON(&buf, electronics, nested.ExportOptions{Fields: []string{"missing"}})
+	assert.EqualError(suite.T(), err, "export: unknown column missing")
+
+	books := Category{Name: "Books"}
+	suite.db.Save(&books)
+	err = suite.plugin.ExportJSON(&buf, &books, nested.ExportOptions{})
+	assert.EqualError(suite.T(), err, "export: *nested_test.Category does not use the nested set strategy")
+	assert.Empty(suite.T(), buf.String())
+}
+
+func TestExportJSONTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportJSONTestSuite))
+}
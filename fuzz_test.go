@@ -0,0 +1,239 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/vcraescu/gorm-nested"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// fuzzMaxSteps bounds the operations decoded from one input
+const fuzzMaxSteps = 40
+
+// referenceTree is the in-memory model of the tree the fuzzed operations are checked
+// against. The children are kept in their order, the roots being the children of 0.
+type referenceTree struct {
+	parents  map[uint]uint
+	children map[uint][]uint
+}
+
+func newReferenceTree() *referenceTree {
+	return &referenceTree{parents: map[uint]uint{}, children: map[uint][]uint{}}
+}
+
+// ids returns the nodes in ascending order
+func (r *referenceTree) ids() []uint {
+	ids := make([]uint, 0, len(r.parents))
+	for id := range r.parents {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func (r *referenceTree) insert(id, parent uint) {
+	r.parents[id] = parent
+	r.children[parent] = append(r.children[parent], id)
+}
+
+func (r *referenceTree) detach(id uint) {
+	siblings := r.children[r.parents[id]]
+	for i, sibling := range siblings {
+		if sibling == id {
+			r.children[r.parents[id]] = append(siblings[:i:i], siblings[i+1:]...)
+
+			return
+		}
+	}
+}
+
+// move appends the node to the children of parent, unless it is already one of them
+func (r *referenceTree) move(id, parent uint) {
+	if r.parents[id] == parent {
+		return
+	}
+
+	r.detach(id)
+	r.insert(id, parent)
+}
+
+func (r *referenceTree) remove(id uint) {
+	r.detach(id)
+
+	var walk func(id uint)
+	walk = func(id uint) {
+		for _, child := range r.children[id] {
+			walk(child)
+		}
+
+		delete(r.parents, id)
+		delete(r.children, id)
+	}
+	walk(id)
+}
+
+// contains reports whether id is ancestor or one of its descendants
+func (r *referenceTree) contains(ancestor, id uint) bool {
+	for ; id != 0; id = r.parents[id] {
+		if id == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expected returns the nodes with the values the nested set should store
+func (r *referenceTree) expected() map[uint]Taxon {
+	nodes := map[uint]Taxon{}
+	value := 0
+
+	var walk func(parent uint, level int)
+	walk = func(parent uint, level int) {
+		for _, id := range r.children[parent] {
+			value++
+			left := value
+			walk(id, level+1)
+			value++
+			nodes[id] = Taxon{ID: id, ParentID: parent, TreeLeft: left, TreeRight: value, TreeLevel: level}
+		}
+	}
+	walk(0, 0)
+
+	return nodes
+}
+
+// fuzzTree applies the operations decoded from data, three bytes each, through gorm and
+// checks the stored tree against the reference after each of them
+func fuzzTree(t *testing.T, data []byte) {
+	db, err := gorm.Open("sqlite3", filepath.Join(t.TempDir(), "fuzz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// gorm logs every registered callback
+	db.SetLogger(gorm.Logger{LogWriter: log.New(ioutil.Discard, "", 0)})
+	db.AutoMigrate(&Taxon{})
+
+	p, err := nested.Register(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := newReferenceTree()
+	for step := 0; step < fuzzMaxSteps && len(data) >= 3; step++ {
+		op, a, b := data[0], data[1], data[2]
+		data = data[3:]
+
+		ids := ref.ids()
+		pick := func(i byte) uint {
+			if i := int(i) % (len(ids) + 1); i < len(ids) {
+				return ids[i]
+			}
+
+			return 0
+		}
+
+		var desc string
+		switch {
+		case op%3 == 0 || len(ids) == 0:
+			parent := pick(a)
+			node := Taxon{Name: fmt.Sprintf("taxon %d", step)}
+			if parent != 0 {
+				node.Parent = findTaxon(t, db, parent)
+			}
+
+			desc = fmt.Sprintf("insert under %d", parent)
+			if err := db.Create(&node).Error; err != nil {
+				t.Fatalf("step %d: %s: %s", step, desc, err)
+			}
+
+			ref.insert(node.ID, parent)
+		case op%3 == 1:
+			id, parent := ids[int(a)%len(ids)], pick(b)
+			if parent != 0 && ref.contains(id, parent) {
+				continue
+			}
+
+			var to nested.Interface
+			if parent != 0 {
+				to = findTaxon(t, db, parent)
+			}
+
+			desc = fmt.Sprintf("move %d under %d", id, parent)
+			if err := p.Move(findTaxon(t, db, id), to); err != nil {
+				t.Fatalf("step %d: %s: %s", step, desc, err)
+			}
+
+			ref.move(id, parent)
+		default:
+			id := ids[int(a)%len(ids)]
+			desc = fmt.Sprintf("delete %d", id)
+			if err := db.Delete(findTaxon(t, db, id)).Error; err != nil {
+				t.Fatalf("step %d: %s: %s", step, desc, err)
+			}
+
+			ref.remove(id)
+		}
+
+		checkTree(t, db, p, ref, fmt.Sprintf("step %d: %s", step, desc))
+	}
+}
+
+func findTaxon(t *testing.T, db *gorm.DB, id uint) *Taxon {
+	taxon := &Taxon{}
+	if err := db.Where("id = ?", id).First(taxon).Error; err != nil {
+		t.Fatalf("taxon %d: %s", id, err)
+	}
+
+	return taxon
+}
+
+// checkTree compares the stored nodes with the reference and verifies the whole tree
+func checkTree(t *testing.T, db *gorm.DB, p nested.Plugin, ref *referenceTree, step string) {
+	var taxons []Taxon
+	if err := db.Order("id").Find(&taxons).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ref.expected()
+	if len(taxons) != len(expected) {
+		t.Fatalf("%s: %d nodes stored instead of %d", step, len(taxons), len(expected))
+	}
+
+	for _, taxon := range taxons {
+		want, ok := expected[taxon.ID]
+		got := Taxon{ID: taxon.ID, ParentID: taxon.ParentID, TreeLeft: taxon.TreeLeft, TreeRight: taxon.TreeRight, TreeLevel: taxon.TreeLevel}
+		if !ok || got != want {
+			t.Fatalf("%s: stored %+v instead of %+v", step, got, want)
+		}
+	}
+
+	problems, err := p.Verify(&Taxon{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(problems) > 0 {
+		t.Fatalf("%s: %v", step, problems)
+	}
+}
+
+// FuzzTree checks the nested set after random sequences of inserts, moves and deletes,
+// run with go test -fuzz FuzzTree
+func FuzzTree(f *testing.F) {
+	// a chain, moves of subtrees to the left, to the right and to the roots, deletes
+	f.Add([]byte{0, 0, 0, 0, 1, 0, 0, 2, 0, 0, 3, 0, 1, 3, 1, 1, 2, 0, 2, 1, 0})
+	f.Add([]byte{0, 9, 0, 0, 9, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 4, 1, 4, 0, 2, 0, 0})
+	f.Add([]byte{0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 2, 0, 1, 1, 5, 1, 3, 0, 2, 2, 0, 0, 0, 0})
+	f.Add([]byte{3, 0, 0, 3, 1, 0, 6, 2, 0, 4, 2, 0, 4, 0, 9, 5, 1, 0, 7, 0, 1})
+
+	f.Fuzz(fuzzTree)
+}
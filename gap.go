@@ -0,0 +1,191 @@
+package nested
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// WithGap enables gap numbering. A new node spans up to gap values, leaving room inside
+// it for its children, and takes at most half of the free values of its parent, leaving
+// the other half for the following siblings. When the parent has no free values left,
+// its children are compacted first and, only if that is not enough, the values are
+// renumbered inside the nearest ancestor which still has free values. The tree from the
+// right of the parent is shifted only when no ancestor has any. Deleted subtrees leave
+// their values free.
+func WithGap(gap int) Option {
+	return func(p *Plugin) {
+		if gap < 2 {
+			gap = 2
+		}
+
+		p.gap = gap
+	}
+}
+
+// allocateInGap returns the left and right values of a new last child of parent, making
+// room for it if needed
+func (p *Plugin) allocateInGap(parent Interface, scope *gorm.Scope) (int, int) {
+	last := p.lastChildRight(parent, scope)
+	if getTreeRight(parent)-last-1 < 2 {
+		last = p.compactChildren(parent, scope)
+	}
+
+	free := getTreeRight(parent) - last - 1
+	if free < 2 {
+		free += p.growNode(parent, free, scope)
+	}
+
+	return last + 1, last + p.nodeSpan(free)
+}
+
+// nodeSpan returns the number of values taken by a new node out of the free values of
+// its parent
+func (p *Plugin) nodeSpan(free int) int {
+	span := free / 2
+	if span > p.gap {
+		span = p.gap
+	}
+	if span < 2 {
+		span = 2
+	}
+
+	return span
+}
+
+// growNode adds free values at the end of node, which has free of them, and returns the
+// number of values added. They are taken from the nearest ancestor having enough free
+// values after its last child, only the values between the node and that child being
+// shifted, or by shifting the tree from the right of the node when none has.
+func (p *Plugin) growNode(node Interface, free int, scope *gorm.Scope) int {
+	need, want := 2-free, p.gap-free
+
+	ancestor := node
+	for !isRoot(ancestor) {
+		parent, _ := findParent(ancestor, scope)
+		if scope.HasError() {
+			return 0
+		}
+
+		ancestor = parent
+		room := getTreeRight(ancestor) - p.lastChildRight(ancestor, scope) - 1
+		if room < need {
+			continue
+		}
+
+		grow := room / 2
+		if grow < need {
+			grow = need
+		}
+		if grow > want {
+			grow = want
+		}
+
+		p.shiftInside(scope, node, ancestor, grow)
+
+		return grow
+	}
+
+	p.openGap(scope, getTreeRight(node), want)
+
+	return want
+}
+
+// shiftInside shifts by offset the left/right values from the right value of node up to
+// the right value of its ancestor, which is left as it is
+func (p *Plugin) shiftInside(scope *gorm.Scope, node, ancestor Interface, offset int) {
+	db := scope.DB().Set(settingIgnoreUpdate, true)
+	from, to := getTreeRight(node), getTreeRight(ancestor)-1
+	res := db.
+		Table(scope.TableName()).
+		Where(p.expr(":tree_right >= ? AND :tree_right <= ?"), from, to).
+		Update(p.treeRightName, gorm.Expr(p.expr(":tree_right + ?"), offset))
+	db.
+		Table(scope.TableName()).
+		Where(p.expr(":tree_left >= ? AND :tree_left <= ?"), from, to).
+		Update(p.treeLeftName, gorm.Expr(p.expr(":tree_left + ?"), offset))
+	recordChange(scope, Change{
+		Kind:         ChangeShifted,
+		From:         from,
+		To:           to,
+		Offset:       offset,
+		RowsAffected: res.RowsAffected,
+	})
+}
+
+// lastChildRight returns the right value of the last child of parent or the
+// parent left value when it has no children
+func (p *Plugin) lastChildRight(parent Interface, scope *gorm.Scope) int {
+	last := newNodePtrFromValue(parent)
+	notFound := scope.NewDB().
+		Where(
+			p.expr(":tree_left > ? AND :tree_right < ? AND :tree_level = ?"),
+			getTreeLeft(parent),
+			getTreeRight(parent),
+			getTreeLevel(parent)+1,
+		).
+		Order(p.expr(":tree_right desc")).
+		First(last).
+		RecordNotFound()
+	if notFound {
+		return getTreeLeft(parent)
+	}
+
+	return getTreeRight(last)
+}
+
+// compactChildren moves the children subtrees of parent to the left, removing the
+// free values between them, and returns the right value of the last child
+func (p *Plugin) compactChildren(parent Interface, scope *gorm.Scope) int {
+	db := scope.DB().Set(settingIgnoreUpdate, true)
+	cursor := getTreeLeft(parent) + 1
+
+	rows, err := scope.NewDB().
+		Table(scope.TableName()).
+		Select(p.expr(":tree_left, :tree_right")).
+		Where(
+			p.expr(":tree_left > ? AND :tree_right < ? AND :tree_level = ?"),
+			getTreeLeft(parent),
+			getTreeRight(parent),
+			getTreeLevel(parent)+1,
+		).
+		Order(p.expr(":tree_left")).
+		Rows()
+	if err != nil {
+		panic(err)
+	}
+
+	var bounds [][2]int
+	for rows.Next() {
+		var left, right int
+		if err := rows.Scan(&left, &right); err != nil {
+			rows.Close()
+			panic(err)
+		}
+
+		bounds = append(bounds, [2]int{left, right})
+	}
+	rows.Close()
+
+	for _, b := range bounds {
+		offset := cursor - b[0]
+		if offset != 0 {
+			res := db.
+				Table(scope.TableName()).
+				Where(p.expr(":tree_left >= ? AND :tree_right <= ?"), b[0], b[1]).
+				Updates(map[string]interface{}{
+					p.treeLeftName:  gorm.Expr(p.expr(":tree_left + ?"), offset),
+					p.treeRightName: gorm.Expr(p.expr(":tree_right + ?"), offset),
+				})
+			recordChange(scope, Change{
+				Kind:         ChangeShifted,
+				From:         b[0],
+				To:           b[1],
+				Offset:       offset,
+				RowsAffected: res.RowsAffected,
+			})
+		}
+
+		cursor = b[1] + offset + 1
+	}
+
+	return cursor - 1
+}
@@ -0,0 +1,198 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"testing"
+)
+
+type GapTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *GapTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Taxon{})
+
+	_, err = nested.Register(suite.db, nested.WithGap(10))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *GapTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *GapTestSuite) assertBounds(name string, left, right, level int) {
+	var taxon Taxon
+	assert.False(suite.T(), suite.db.First(&taxon, "name = ?", name).RecordNotFound())
+	assert.Equal(suite.T(), left, taxon.TreeLeft, name)
+	assert.Equal(suite.T(), right, taxon.TreeRight, name)
+	assert.Equal(suite.T(), level, taxon.TreeLevel, name)
+}
+
+// bounds returns the left, right and level values of every taxon by name
+func (suite *GapTestSuite) bounds() map[string][3]int {
+	var taxons []Taxon
+	assert.NoError(suite.T(), suite.db.Find(&taxons).Error)
+
+	bounds := map[string][3]int{}
+	for _, taxon := range taxons {
+		bounds[taxon.Name] = [3]int{taxon.TreeLeft, taxon.TreeRight, taxon.TreeLevel}
+	}
+
+	return bounds
+}
+
+func (suite *GapTestSuite) TestInsertUsesGap() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	suite.db.Save(&television)
+
+	suite.assertBounds("Electronics", 1, 10, 0)
+	suite.assertBounds("Television", 2, 5, 1)
+
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	cs, ok := nested.Changes(suite.db.Save(&radio))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeInserted, From: 6, To: 7, RowsAffected: 1},
+	}, cs.Changes)
+
+	tube := Taxon{Name: "Tube", Parent: &television}
+	suite.db.Save(&tube)
+
+	suite.assertBounds("Electronics", 1, 10, 0)
+	suite.assertBounds("Television", 2, 5, 1)
+	suite.assertBounds("Tube", 3, 4, 2)
+	suite.assertBounds("Radio", 6, 7, 1)
+
+	suite.db.First(&radio, radio.ID)
+	cs, ok = nested.Changes(suite.db.Delete(&radio))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeDeleted, From: 6, To: 7, RowsAffected: 1},
+	}, cs.Changes)
+	suite.assertBounds("Electronics", 1, 10, 0)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{}, nested.WithGap(10))
+}
+
+func (suite *GapTestSuite) TestInsertGrandchildKeepsOtherRows() {
+	nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		  Radio
+		Books
+	`)
+	before := suite.bounds()
+
+	var television Taxon
+	suite.db.First(&television, "name = ?", "Television")
+
+	tube := Taxon{Name: "Tube", Parent: &television}
+	cs, ok := nested.Changes(suite.db.Save(&tube))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeInserted, From: 3, To: 4, RowsAffected: 1},
+	}, cs.Changes)
+
+	after := suite.bounds()
+	assert.Equal(suite.T(), [3]int{3, 4, 2}, after["Tube"])
+
+	delete(after, "Tube")
+	assert.Equal(suite.T(), before, after)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{}, nested.WithGap(10))
+}
+
+func (suite *GapTestSuite) TestRenumberInsideAncestor() {
+	nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    Tube
+		  Radio
+		Books
+	`)
+	suite.assertBounds("Electronics", 1, 10, 0)
+	suite.assertBounds("Television", 2, 5, 1)
+	suite.assertBounds("Radio", 6, 7, 1)
+	suite.assertBounds("Books", 11, 20, 0)
+
+	var television Taxon
+	suite.db.First(&television, "name = ?", "Television")
+
+	// Television is full, the values are taken from the free ones of Electronics
+	plasma := Taxon{Name: "Plasma", Parent: &television}
+	cs, ok := nested.Changes(suite.db.Save(&plasma))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{
+		{Kind: nested.ChangeShifted, From: 5, To: 9, Offset: 2, RowsAffected: 2},
+		{Kind: nested.ChangeInserted, From: 5, To: 6, RowsAffected: 1},
+	}, cs.Changes)
+
+	suite.assertBounds("Electronics", 1, 10, 0)
+	suite.assertBounds("Television", 2, 7, 1)
+	suite.assertBounds("Plasma", 5, 6, 2)
+	suite.assertBounds("Radio", 8, 9, 1)
+	suite.assertBounds("Books", 11, 20, 0)
+
+	// no ancestor has free values left, the tree is shifted by the gap
+	suite.db.First(&television, television.ID)
+	lcd := Taxon{Name: "LCD", Parent: &television}
+	suite.db.Save(&lcd)
+
+	suite.assertBounds("Electronics", 1, 20, 0)
+	suite.assertBounds("Television", 2, 17, 1)
+	suite.assertBounds("LCD", 7, 11, 2)
+	suite.assertBounds("Radio", 18, 19, 1)
+	suite.assertBounds("Books", 21, 30, 0)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{}, nested.WithGap(10))
+}
+
+func (suite *GapTestSuite) TestCompactChildrenWhenGapIsExhausted() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	a := Taxon{Name: "A", Parent: &television}
+	suite.db.Save(&a)
+
+	b := Taxon{Name: "B", Parent: &television}
+	suite.db.Save(&b)
+
+	suite.assertBounds("Television", 2, 7, 1)
+	suite.assertBounds("A", 3, 4, 2)
+	suite.assertBounds("B", 5, 6, 2)
+
+	suite.db.Delete(&a)
+
+	// the values freed by A are enough, nothing is taken from Electronics
+	suite.db.First(&television, television.ID)
+	c := Taxon{Name: "C", Parent: &television}
+	suite.db.Save(&c)
+
+	suite.assertBounds("Electronics", 1, 10, 0)
+	suite.assertBounds("Television", 2, 7, 1)
+	suite.assertBounds("B", 3, 4, 2)
+	suite.assertBounds("C", 5, 6, 2)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{}, nested.WithGap(10))
+}
+
+func TestGapTestSuite(t *testing.T) {
+	suite.Run(t, new(GapTestSuite))
+}
@@ -1,10 +1,17 @@
 module github.com/vcraescu/gorm-nested
 
+go 1.18
+
 require (
 	github.com/davecgh/go-spew v1.1.1
-	github.com/jinzhu/gorm v1.9.2
-	github.com/jinzhu/inflection v0.0.0-20180308033659-04140366298a // indirect
-	github.com/mattn/go-sqlite3 v1.10.0 // indirect
-	github.com/stretchr/objx v0.1.1 // indirect
+	github.com/jinzhu/gorm v1.9.16
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.3.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.0 // indirect
+	github.com/stretchr/objx v0.1.1 // indirect
 )
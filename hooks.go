@@ -0,0 +1,65 @@
+package nested
+
+// BeforeMover can be implemented by the model to be notified before the node
+// subtree is moved from one parent to another. Saving a node only moves it when
+// its parent changed, so the hooks do not run for the other updates. Returning
+// an error aborts the move and rolls back the update.
+type BeforeMover interface {
+	BeforeMove(from, to Interface) error
+}
+
+// AfterMover can be implemented by the model to be notified after the node
+// subtree was moved. Returning an error rolls back the update.
+type AfterMover interface {
+	AfterMove(from, to Interface) error
+}
+
+// BeforeSubtreeDeleter can be implemented by the model to be notified before
+// the descendants of a deleted node are removed. ids contains the primary keys
+// of the node and of all its descendants. Returning an error aborts the delete.
+type BeforeSubtreeDeleter interface {
+	BeforeSubtreeDelete(ids []interface{}) error
+}
+
+// AfterSubtreeDeleter can be implemented by the model to be notified after a
+// node and all its descendants were deleted. Returning an error rolls back
+// the delete.
+type AfterSubtreeDeleter interface {
+	AfterSubtreeDelete(ids []interface{}) error
+}
+
+func callBeforeMove(node, from, to Interface) error {
+	h, ok := node.(BeforeMover)
+	if !ok {
+		return nil
+	}
+
+	return h.BeforeMove(from, to)
+}
+
+func callAfterMove(node, from, to Interface) error {
+	h, ok := node.(AfterMover)
+	if !ok {
+		return nil
+	}
+
+	return h.AfterMove(from, to)
+}
+
+func callBeforeSubtreeDelete(node Interface, ids []interface{}) error {
+	h, ok := node.(BeforeSubtreeDeleter)
+	if !ok {
+		return nil
+	}
+
+	return h.BeforeSubtreeDelete(ids)
+}
+
+func callAfterSubtreeDelete(node Interface, ids []interface{}) error {
+	h, ok := node.(AfterSubtreeDeleter)
+	if !ok {
+		return nil
+	}
+
+	return h.AfterSubtreeDelete(ids)
+}
@@ -0,0 +1,213 @@
+package nested_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+var (
+	hookEvents    []string
+	errAbortHook  = errors.New("hook aborted")
+	abortMoveTo   string
+	abortDeleteOf uint
+)
+
+type HookedTaxon struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *HookedTaxon `gorm:"association_autoupdate:false"`
+	TreeLeft  int          `gorm-nested:"left"`
+	TreeRight int          `gorm-nested:"right"`
+	TreeLevel int          `gorm-nested:"level"`
+}
+
+func (t HookedTaxon) GetParentID() interface{} {
+	return t.ParentID
+}
+
+func (t HookedTaxon) GetParent() nested.Interface {
+	return t.Parent
+}
+
+func (t HookedTaxon) BeforeMove(from, to nested.Interface) error {
+	hookEvents = append(hookEvents, fmt.Sprintf("before move %s: %s -> %s", t.Name, hookedName(from), hookedName(to)))
+	if hookedName(to) == abortMoveTo {
+		return errAbortHook
+	}
+
+	return nil
+}
+
+func (t HookedTaxon) AfterMove(from, to nested.Interface) error {
+	hookEvents = append(hookEvents, fmt.Sprintf("after move %s: %s -> %s", t.Name, hookedName(from), hookedName(to)))
+
+	return nil
+}
+
+func (t HookedTaxon) BeforeSubtreeDelete(ids []interface{}) error {
+	hookEvents = append(hookEvents, fmt.Sprintf("before delete %s: %v", t.Name, ids))
+	if t.ID == abortDeleteOf {
+		return errAbortHook
+	}
+
+	return nil
+}
+
+func (t HookedTaxon) AfterSubtreeDelete(ids []interface{}) error {
+	hookEvents = append(hookEvents, fmt.Sprintf("after delete %s: %v", t.Name, ids))
+
+	return nil
+}
+
+func hookedName(node nested.Interface) string {
+	if node == nil {
+		return "<root>"
+	}
+
+	return node.(*HookedTaxon).Name
+}
+
+type HooksTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *HooksTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&HookedTaxon{})
+
+	_, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	hookEvents = nil
+	abortMoveTo = ""
+	abortDeleteOf = 0
+}
+
+func (suite *HooksTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *HooksTestSuite) createTree() (*HookedTaxon, *HookedTaxon, *HookedTaxon) {
+	electronics := HookedTaxon{Name: "Electronics"}
+	television := HookedTaxon{Name: "Television", Parent: &electronics}
+	tube := HookedTaxon{Name: "Tube", Parent: &television}
+	suite.db.Save(&tube)
+
+	hookEvents = nil
+
+	return &electronics, &television, &tube
+}
+
+func (suite *HooksTestSuite) TestMoveHooks() {
+	electronics, television, tube := suite.createTree()
+
+	tube.Parent = electronics
+	tube.ParentID = electronics.ID
+	assert.NoError(suite.T(), suite.db.Save(tube).Error)
+
+	assert.Equal(suite.T(), []string{
+		"before move Tube: Television -> Electronics",
+		"after move Tube: Television -> Electronics",
+	}, hookEvents)
+	assert.Equal(suite.T(), 1, tube.TreeLevel)
+
+	hookEvents = nil
+	television.Name = "TV"
+	assert.NoError(suite.T(), suite.db.Save(television).Error)
+	assert.Empty(suite.T(), hookEvents)
+
+	hookEvents = nil
+	television.Parent = nil
+	television.ParentID = 0
+	assert.NoError(suite.T(), suite.db.Save(television).Error)
+	assert.Equal(suite.T(), []string{
+		"before move TV: Electronics -> <root>",
+		"after move TV: Electronics -> <root>",
+	}, hookEvents)
+}
+
+func (suite *HooksTestSuite) TestBeforeMoveAbortsMove() {
+	electronics, television, tube := suite.createTree()
+
+	abortMoveTo = "Electronics"
+	tube.Parent = electronics
+	tube.ParentID = electronics.ID
+	assert.Equal(suite.T(), errAbortHook, suite.db.Save(tube).Error)
+
+	var stored HookedTaxon
+	suite.db.First(&stored, tube.ID)
+	assert.Equal(suite.T(), television.ID, stored.ParentID)
+	assert.Equal(suite.T(), 3, stored.TreeLeft)
+	assert.Equal(suite.T(), 4, stored.TreeRight)
+	assert.Equal(suite.T(), 2, stored.TreeLevel)
+}
+
+func (suite *HooksTestSuite) TestMoveToUnknownParent() {
+	_, television, tube := suite.createTree()
+
+	tube.Parent = nil
+	tube.ParentID = 99
+	assert.EqualError(suite.T(), suite.db.Save(tube).Error, "parent not found: 99")
+	assert.Empty(suite.T(), hookEvents)
+
+	var stored HookedTaxon
+	suite.db.First(&stored, tube.ID)
+	assert.Equal(suite.T(), television.ID, stored.ParentID)
+	assert.Equal(suite.T(), 3, stored.TreeLeft)
+	assert.Equal(suite.T(), 4, stored.TreeRight)
+	assert.Equal(suite.T(), 2, stored.TreeLevel)
+}
+
+func (suite *HooksTestSuite) TestSubtreeDeleteHooks() {
+	electronics, television, tube := suite.createTree()
+
+	assert.NoError(suite.T(), suite.db.Delete(television).Error)
+	assert.Equal(suite.T(), []string{
+		fmt.Sprintf("before delete Television: [%d %d]", television.ID, tube.ID),
+		fmt.Sprintf("after delete Television: [%d %d]", television.ID, tube.ID),
+	}, hookEvents)
+
+	var count int
+	suite.db.Model(&HookedTaxon{}).Count(&count)
+	assert.Equal(suite.T(), 1, count)
+
+	suite.db.First(electronics)
+	assert.Equal(suite.T(), 1, electronics.TreeLeft)
+	assert.Equal(suite.T(), 2, electronics.TreeRight)
+}
+
+func (suite *HooksTestSuite) TestBeforeSubtreeDeleteAbortsDelete() {
+	_, television, _ := suite.createTree()
+
+	abortDeleteOf = television.ID
+	assert.Equal(suite.T(), errAbortHook, suite.db.Delete(television).Error)
+
+	var count int
+	suite.db.Model(&HookedTaxon{}).Count(&count)
+	assert.Equal(suite.T(), 3, count)
+}
+
+func TestHooksTestSuite(t *testing.T) {
+	suite.Run(t, new(HooksTestSuite))
+}
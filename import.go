@@ -0,0 +1,311 @@
+package nested
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+)
+
+// Format is the encoding of a tree document
+type Format string
+
+const (
+	// JSON nested {"node": ..., "children": [...]} objects as written by ExportJSON
+	JSON Format = "json"
+	// YAML the same structure as JSON
+	YAML Format = "yaml"
+)
+
+// importedNode is a decoded node with its children
+type importedNode struct {
+	node     Interface
+	children []*importedNode
+}
+
+// Import inserts the nodes of a document made of one or a list of nested
+// {"node": ..., "children": [...]} objects as the last children of parent, or as the
+// last roots when the primary key of parent is blank, e.g. &Taxon{}. The node keys are
+// column or field names. The primary key, the associations, the parent foreign key and
+// the tree columns are set by the import and their values are ignored, so the output of
+// ExportJSON can be imported as it is. The whole document is validated first, then the
+// left/right/level values are computed and every node is inserted once, without
+// renumbering the tree for each of them. It requires the nested set strategy.
+func (p *Plugin) Import(r io.Reader, format Format, parent Interface) error {
+	return p.ImportContext(context.Background(), r, format, parent)
+}
+
+// ImportContext is Import bound to ctx. The import runs in a transaction rolled back on
+// error or when ctx is done.
+func (p *Plugin) ImportContext(ctx context.Context, r io.Reader, format Format, parent Interface) error {
+	p, end := p.observe("import")
+	defer end()
+
+	p = p.withColumns(parent)
+
+	if p.strategyOf(parent) != NestedSet {
+		return fmt.Errorf("import: %T does not use the nested set strategy", parent)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case JSON:
+		if err := validateJSON(data); err != nil {
+			return err
+		}
+	case YAML:
+	default:
+		return fmt.Errorf("import: unknown format %q", format)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("import: %s", err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return fmt.Errorf("import: empty document")
+	}
+
+	nodes, err := p.decodeImportedNodes(doc.Content[0], parent)
+	if err != nil {
+		return err
+	}
+
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.insertImportedNodes(ctx, nodes, parent)
+	})
+}
+
+func (p *Plugin) decodeImportedNodes(n *yaml.Node, model Interface) ([]*importedNode, error) {
+	items := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		items = n.Content
+	}
+
+	var nodes []*importedNode
+	for _, item := range items {
+		node, err := p.decodeImportedNode(item, model)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+func (p *Plugin) decodeImportedNode(n *yaml.Node, model Interface) (*importedNode, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, importError(n, "expected an object with node and children")
+	}
+
+	imported := &importedNode{}
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		switch key.Value {
+		case "node":
+			node, err := p.decodeNodeValues(value, model)
+			if err != nil {
+				return nil, err
+			}
+
+			imported.node = node
+		case "children":
+			if value.Kind != yaml.SequenceNode {
+				return nil, importError(value, "children must be a list")
+			}
+
+			for _, item := range value.Content {
+				child, err := p.decodeImportedNode(item, model)
+				if err != nil {
+					return nil, err
+				}
+
+				imported.children = append(imported.children, child)
+			}
+		default:
+			return nil, importError(key, "unknown key %q", key.Value)
+		}
+	}
+
+	if imported.node == nil {
+		return nil, importError(n, "node is missing")
+	}
+
+	return imported, nil
+}
+
+// decodeNodeValues decodes the columns of a node object into a new model instance
+func (p *Plugin) decodeNodeValues(n *yaml.Node, model Interface) (Interface, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, importError(n, "node must be an object")
+	}
+
+	node := newNodePtrFromValue(model)
+	scope := p.db.NewScope(node)
+	computed := map[string]bool{
+		p.treeLeftName:      true,
+		p.treeRightName:     true,
+		p.treeLevelName:     true,
+		p.pathName:          true,
+		p.childrenCountName: true,
+	}
+	if f, ok := parentField(scope); ok {
+		computed[f.Relationship.ForeignDBNames[0]] = true
+	}
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		f, ok := scope.FieldByName(key.Value)
+		if !ok || f.IsIgnored {
+			return nil, importError(key, "unknown column %q", key.Value)
+		}
+
+		// the values written by ExportJSON which the import sets itself
+		if f.IsPrimaryKey || f.Relationship != nil || computed[f.DBName] {
+			continue
+		}
+
+		if err := value.Decode(f.Field.Addr().Interface()); err != nil {
+			return nil, importError(value, "invalid %s value %q for %q", f.Field.Type(), value.Value, key.Value)
+		}
+	}
+
+	return node, nil
+}
+
+func (p *Plugin) insertImportedNodes(ctx context.Context, nodes []*importedNode, parent Interface) error {
+	scope := p.db.NewScope(parent)
+	left, level := 1, 0
+	path := pathSeparator
+	if scope.PrimaryKeyZero() {
+		parent = nil
+
+		var max int
+		err := p.db.
+			Table(scope.TableName()).
+			Select(p.expr("COALESCE(MAX(:tree_right), 0)")).
+			Row().
+			Scan(&max)
+		if err != nil {
+			return err
+		}
+
+		left = max + 1
+	} else {
+		if err := p.db.First(parent).Error; err != nil {
+			return err
+		}
+
+		left = getTreeRight(parent)
+		level = getTreeLevel(parent) + 1
+		path = getTreePath(parent)
+
+		// every imported node takes two values from the right of the parent
+		p.openGap(scope, left, 2*countImportedNodes(nodes))
+		if p.childrenCountName != "" {
+			p.addChildrenCount(scope, scope.PrimaryKeyValue(), len(nodes))
+		}
+	}
+
+	_, err := p.insertImportedLevel(ctx, nodes, parent, left, level, path)
+
+	return err
+}
+
+// insertImportedLevel inserts nodes and their subtrees from the left value, returning
+// the value following the last right value
+func (p *Plugin) insertImportedLevel(
+	ctx context.Context,
+	nodes []*importedNode,
+	parent Interface,
+	left, level int,
+	path string,
+) (int, error) {
+	for _, n := range nodes {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		if parent != nil {
+			if err := setParent(p.db, n.node, parent); err != nil {
+				return 0, err
+			}
+		}
+
+		setTagValue(n.node, "left", left)
+		setTagValue(n.node, "right", left+2*countImportedNodes(n.children)+1)
+		setTagValue(n.node, "level", level)
+		setTagValue(n.node, "children_count", len(n.children))
+
+		err := p.db.
+			Set(settingIgnoreCreate, true).
+			Set("gorm:save_associations", false).
+			Create(n.node).
+			Error
+		if err != nil {
+			return 0, err
+		}
+
+		scope := p.db.NewScope(n.node)
+		childPath := nodePath(path, scope.PrimaryKeyValue())
+		if p.pathName != "" {
+			updateCurrentNode(n.node, map[string]interface{}{p.pathName: childPath}, scope)
+			setTagValue(n.node, "path", childPath)
+		}
+
+		next, err := p.insertImportedLevel(ctx, n.children, n.node, left+1, level+1, childPath)
+		if err != nil {
+			return 0, err
+		}
+
+		left = next + 1
+	}
+
+	return left, nil
+}
+
+func countImportedNodes(nodes []*importedNode) int {
+	count := len(nodes)
+	for _, n := range nodes {
+		count += countImportedNodes(n.children)
+	}
+
+	return count
+}
+
+// validateJSON reports the line of a JSON syntax error, which the YAML decoder would
+// not report the same way
+func validateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var v interface{}
+	err := dec.Decode(&v)
+	if err == nil && dec.More() {
+		err = fmt.Errorf("unexpected data after the document")
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	offset := dec.InputOffset()
+	if serr, ok := err.(*json.SyntaxError); ok {
+		offset = serr.Offset
+	}
+
+	return fmt.Errorf("import: line %d: %s", bytes.Count(data[:offset], []byte("\n"))+1, err)
+}
+
+func importError(n *yaml.Node, format string, args ...interface{}) error {
+	return fmt.Errorf("import: line %d: %s", n.Line, fmt.Sprintf(format, args...))
+}
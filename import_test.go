@@ -0,0 +1,194 @@
+package nested_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const taxonomyYAML = `
+- node:
+    name: Electronics
+  children:
+    - node: {name: Television}
+      children:
+        - node: {name: LCD}
+        - node: {name: Plasma}
+    - node: {name: Radio}
+- node: {name: Books}
+`
+
+type ImportTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *ImportTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Taxon{}, &Page{}, &Folder{})
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *ImportTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *ImportTestSuite) taxons() map[string][4]int {
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	bounds := map[string][4]int{}
+	for _, t := range taxons {
+		bounds[t.Name] = [4]int{t.TreeLeft, t.TreeRight, t.TreeLevel, int(t.ParentID)}
+	}
+
+	return bounds
+}
+
+func (suite *ImportTestSuite) TestImportRootsFromYAML() {
+	root := Taxon{Name: "Root"}
+	suite.db.Save(&root)
+
+	assert.NoError(suite.T(), suite.plugin.Import(strings.NewReader(taxonomyYAML), nested.YAML, &Taxon{}))
+	assert.Equal(suite.T(), map[string][4]int{
+		"Root":        {1, 2, 0, 0},
+		"Electronics": {3, 12, 0, 0},
+		"Television":  {4, 9, 1, 2},
+		"LCD":         {5, 6, 2, 3},
+		"Plasma":      {7, 8, 2, 3},
+		"Radio":       {10, 11, 1, 2},
+		"Books":       {13, 14, 0, 0},
+	}, suite.taxons())
+}
+
+func (suite *ImportTestSuite) TestImportChildrenFromJSON() {
+	electronics := Taxon{Name: "Electronics"}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	books := Taxon{Name: "Books"}
+	suite.db.Save(&radio)
+	suite.db.Save(&books)
+
+	doc := `{"node": {"name": "Television"}, "children": [{"node": {"Name": "LCD"}}]}`
+	assert.NoError(suite.T(), suite.plugin.Import(strings.NewReader(doc), nested.JSON, &electronics))
+	assert.Equal(suite.T(), map[string][4]int{
+		"Electronics": {1, 8, 0, 0},
+		"Radio":       {2, 3, 1, 1},
+		"Television":  {4, 7, 1, 1},
+		"LCD":         {5, 6, 2, 4},
+		"Books":       {9, 10, 0, 0},
+	}, suite.taxons())
+
+	problems, err := suite.plugin.Verify(&Taxon{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func (suite *ImportTestSuite) TestImportComputedColumns() {
+	home := Page{Name: "Home"}
+	suite.db.Save(&home)
+
+	doc := "node: {name: About}\nchildren:\n  - node: {name: Team}\n"
+	assert.NoError(suite.T(), suite.plugin.Import(strings.NewReader(doc), nested.YAML, &home))
+
+	var team Page
+	suite.db.First(&team, "name = ?", "Team")
+	assert.Equal(suite.T(), "/1/2/3/", team.Path)
+
+	root := Folder{Name: "Root"}
+	suite.db.Save(&root)
+
+	doc = "[{node: {name: Docs}, children: [{node: {name: Drafts}}]}, {node: {name: Music}}]"
+	assert.NoError(suite.T(), suite.plugin.Import(strings.NewReader(doc), nested.YAML, &root))
+
+	var docs Folder
+	suite.db.First(&root)
+	suite.db.First(&docs, "name = ?", "Docs")
+	assert.Equal(suite.T(), 2, root.ChildrenCount)
+	assert.Equal(suite.T(), 1, docs.ChildrenCount)
+
+	problems, err := suite.plugin.Verify(&Folder{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func (suite *ImportTestSuite) TestImportExportedJSON() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+		Books
+	`)
+
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), suite.plugin.ExportJSON(&buf, nodes["Electronics"], nested.ExportOptions{}))
+	assert.NoError(suite.T(), suite.plugin.Import(&buf, nested.JSON, nodes["Books"]))
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+		Books
+		  Electronics
+		    Television
+		      LCD
+		    Radio
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *ImportTestSuite) TestImportErrors() {
+	tests := []struct {
+		format nested.Format
+		doc    string
+		err    string
+	}{
+		{nested.YAML, "- node: {name: A}\n  children:\n    - node: {name: B}\n      extra: 1\n", `import: line 4: unknown key "extra"`},
+		{nested.YAML, "- node: {name: A}\n  children: {node: {name: B}}\n", "import: line 2: children must be a list"},
+		{nested.YAML, "- children: []\n", "import: line 1: node is missing"},
+		{nested.YAML, "- node: {name: A, color: red}\n", `import: line 1: unknown column "color"`},
+		{nested.YAML, "- node:\n    name: [A]\n", `import: line 2: invalid string value "" for "name"`},
+		{nested.YAML, "- node: [a]\n", "import: line 1: node must be an object"},
+		{nested.YAML, "- node: {name: A\n", "import: yaml: line 1: did not find expected ',' or '}'"},
+		{nested.JSON, "{\n\"node\": {\"name\": \"A\"},\n}", "import: line 3: invalid character '}' looking for beginning of object key string"},
+		{nested.JSON, "", "import: line 1: EOF"},
+		{nested.Format("xml"), "<a/>", `import: unknown format "xml"`},
+	}
+
+	for _, test := range tests {
+		err := suite.plugin.Import(strings.NewReader(test.doc), test.format, &Taxon{})
+		assert.EqualError(suite.T(), err, test.err, test.doc)
+	}
+
+	var count int
+	suite.db.Model(&Taxon{}).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+}
+
+func TestImportTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportTestSuite))
+}
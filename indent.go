@@ -0,0 +1,150 @@
+package nested
+
+import (
+	"context"
+	"fmt"
+)
+
+// Indent makes node the last child of its previous sibling, as an outline editor demotes
+// an item, and reports whether it moved, node being left as it is when it is the first
+// child. The subtree keeps its place in the tree order while Move updates its parent,
+// levels and left/right values. It requires the nested set strategy.
+func (p *Plugin) Indent(node Interface) (bool, error) {
+	return p.IndentContext(context.Background(), node)
+}
+
+// IndentContext is Indent bound to ctx. The move runs in a transaction rolled back when
+// ctx is done.
+func (p *Plugin) IndentContext(ctx context.Context, node Interface) (bool, error) {
+	p, end := p.observe("indent")
+	defer end()
+
+	// the changes are published once the transaction is committed
+	var moved bool
+	err := p.withChanges(node, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			var err error
+			moved, err = tx.indent(node)
+
+			return err
+		})
+	})
+
+	return moved, err
+}
+
+// Outdent makes node the next sibling of its parent, as an outline editor promotes an
+// item, and reports whether it moved, node being left as it is when it is a root. node
+// is moved by Move to the end of the children of its grandparent, then swapped with the
+// siblings of its former parent following it. It requires the nested set strategy.
+func (p *Plugin) Outdent(node Interface) (bool, error) {
+	return p.OutdentContext(context.Background(), node)
+}
+
+// OutdentContext is Outdent bound to ctx. The move runs in a transaction rolled back
+// when ctx is done.
+func (p *Plugin) OutdentContext(ctx context.Context, node Interface) (bool, error) {
+	p, end := p.observe("outdent")
+	defer end()
+
+	// the move and the swap make a single change set, published once the transaction is
+	// committed
+	var moved bool
+	err := p.withChanges(node, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			var err error
+			moved, err = tx.outdent(node)
+
+			return err
+		})
+	})
+
+	return moved, err
+}
+
+func (p *Plugin) indent(node Interface) (bool, error) {
+	p = p.withColumns(node)
+
+	if err := p.reloadNestedSetNode(node, "indent"); err != nil {
+		return false, err
+	}
+
+	sibling, err := p.adjacentSibling(node, false)
+	if err != nil || sibling == nil {
+		return false, err
+	}
+
+	if err := p.move(node, sibling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p *Plugin) outdent(node Interface) (bool, error) {
+	p = p.withColumns(node)
+
+	if err := p.reloadNestedSetNode(node, "outdent"); err != nil {
+		return false, err
+	}
+
+	if isRoot(node) {
+		return false, nil
+	}
+
+	parent, err := p.findNode(node, node.GetParentID())
+	if err != nil {
+		return false, err
+	}
+
+	var grandparent Interface
+	if !isRoot(parent) {
+		if grandparent, err = p.findNode(node, parent.GetParentID()); err != nil {
+			return false, err
+		}
+	}
+
+	if err := p.move(node, grandparent); err != nil {
+		return false, err
+	}
+
+	// the right value of the parent moved to the left when the node was removed from it
+	if err := p.db.First(parent).Error; err != nil {
+		return false, err
+	}
+
+	left, right, after := getTreeLeft(node), getTreeRight(node), getTreeRight(parent)+1
+	if left == after {
+		return true, nil
+	}
+
+	if err := p.swapRanges(p.db.NewScope(node), after, left-1, left, right); err != nil {
+		return false, err
+	}
+
+	setTagValue(node, "left", after)
+	setTagValue(node, "right", right-left+after)
+
+	return true, nil
+}
+
+// reloadNestedSetNode reloads node after checking that it uses the nested set strategy
+// required by op
+func (p *Plugin) reloadNestedSetNode(node Interface, op string) error {
+	if p.strategyOf(node) != NestedSet {
+		return fmt.Errorf("%s: %T does not use the nested set strategy", op, node)
+	}
+
+	return p.db.First(node).Error
+}
+
+// findNode loads the node of the type of model with the primary key id
+func (p *Plugin) findNode(model Interface, id interface{}) (Interface, error) {
+	scope := p.db.NewScope(model)
+	node := newNodePtrFromValue(model)
+	if err := p.db.Where(fmt.Sprintf("%s = ?", scope.Quote(scope.PrimaryKey())), id).First(node).Error; err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
@@ -0,0 +1,194 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"testing"
+)
+
+type IndentTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	plugin    nested.Plugin
+	published []nested.ChangeSet
+}
+
+func (suite *IndentTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.published = nil
+	suite.plugin, err = nested.Register(suite.db, nested.WithChangeListener(func(cs nested.ChangeSet) {
+		suite.published = append(suite.published, cs)
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Page{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *IndentTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *IndentTestSuite) TestIndent() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  About
+		    Team
+		  Blog
+		    Archive
+		  Contact
+	`)
+
+	blog := nodes["Blog"].(*Page)
+	moved, err := suite.plugin.Indent(blog)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), moved)
+	assert.Equal(suite.T(), nodes["About"].(*Page).ID, blog.ParentID)
+	assert.Equal(suite.T(), []int{5, 8, 2}, []int{blog.TreeLeft, blog.TreeRight, blog.TreeLevel})
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  About
+		    Team
+		    Blog
+		      Archive
+		  Contact
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Page{})
+
+	for _, name := range []string{"Home", "About", "Team"} {
+		moved, err = suite.plugin.Indent(nodes[name])
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), moved, name)
+	}
+
+	nestedtest.AssertValid(suite.T(), suite.db, &Page{})
+}
+
+func (suite *IndentTestSuite) TestOutdent() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  About
+		    Team
+		    Staff
+		      Alice
+		    Jobs
+		  Blog
+		Shop
+	`)
+
+	staff := nodes["Staff"].(*Page)
+	moved, err := suite.plugin.Outdent(staff)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), moved)
+	assert.Equal(suite.T(), nodes["Home"].(*Page).ID, staff.ParentID)
+	assert.Equal(suite.T(), []int{8, 11, 1}, []int{staff.TreeLeft, staff.TreeRight, staff.TreeLevel})
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  About
+		    Team
+		    Jobs
+		  Staff
+		    Alice
+		  Blog
+		Shop
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Page{})
+
+	moved, err = suite.plugin.Outdent(nodes["About"])
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), moved)
+
+	moved, err = suite.plugin.Outdent(nodes["Shop"])
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), moved)
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  Staff
+		    Alice
+		  Blog
+		About
+		  Team
+		  Jobs
+		Shop
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Page{})
+}
+
+func (suite *IndentTestSuite) TestIndentThenOutdent() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  About
+		  Blog
+		    Archive
+		  Contact
+	`)
+	suite.published = nil
+
+	for _, fn := range []func(nested.Interface) (bool, error){suite.plugin.Indent, suite.plugin.Outdent} {
+		moved, err := fn(nodes["Blog"])
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), moved)
+	}
+
+	// the outdent publishes the changes of the move and of the swap following it together
+	if assert.Len(suite.T(), suite.published, 2) {
+		var kinds []nested.ChangeKind
+		for _, change := range suite.published[1].Changes {
+			kinds = append(kinds, change.Kind)
+		}
+
+		assert.Equal(suite.T(), "pages", suite.published[1].Table)
+		assert.Equal(suite.T(), []nested.ChangeKind{
+			nested.ChangeMoved,
+			nested.ChangeShifted,
+			nested.ChangeShifted,
+			nested.ChangeShifted,
+			nested.ChangeMoved,
+			nested.ChangeMoved,
+		}, kinds)
+	}
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Page{}, `
+		Home
+		  About
+		  Blog
+		    Archive
+		  Contact
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Page{})
+}
+
+func (suite *IndentTestSuite) TestClosureTable() {
+	books := Category{Name: "Books"}
+	suite.db.Save(&books)
+
+	_, err := suite.plugin.Outdent(&books)
+	assert.EqualError(suite.T(), err, "outdent: *nested_test.Category does not use the nested set strategy")
+}
+
+func TestIndentTestSuite(t *testing.T) {
+	suite.Run(t, new(IndentTestSuite))
+}
@@ -0,0 +1,271 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+// interval is a node encoded as the matrix [[a c] [b d]] having the left bound a/b
+// and the right bound c/d. The k-th child of a node is M * [[1 1] [k k+1]], so its
+// bounds are (a+kc)/(b+kd) and (a+(k+1)c)/(b+(k+1)d). The determinant of every node
+// is 1 or -1, so moving a subtree is a single integer linear transformation.
+type interval struct {
+	a, b, c, d int64
+}
+
+// rootsInterval is the virtual parent of all roots, the k-th root spanning [k, k+1]
+var rootsInterval = interval{a: 0, b: 1, c: 1, d: 0}
+
+// maxIntervalValue is the largest numerator or denominator stored, so that the product of
+// two of them computed by the subtree queries fits in an int64
+const maxIntervalValue = 3037000499
+
+// child returns the k-th child of the node or an error when its bounds exceed
+// maxIntervalValue
+func (m interval) child(k int64) (interval, error) {
+	n, ok := m.mul(interval{a: 1, b: k, c: 1, d: k + 1})
+	if !ok || !n.fits() {
+		return interval{}, fmt.Errorf("intervals: the bounds of child %d exceed %d, the tree is too deep or too wide", k, maxIntervalValue)
+	}
+
+	return n, nil
+}
+
+// childIndex returns the index of the child having the left bound num/den
+func (m interval) childIndex(num, den int64) int64 {
+	if m.c != 0 {
+		return (num - m.a) / m.c
+	}
+
+	return (den - m.b) / m.d
+}
+
+// mul returns the product of the matrices, false when one of the products or sums
+// overflows an int64
+func (m interval) mul(n interval) (interval, bool) {
+	var r interval
+	var ok [4]bool
+	r.a, ok[0] = addMul(m.a, n.a, m.c, n.b)
+	r.b, ok[1] = addMul(m.b, n.a, m.d, n.b)
+	r.c, ok[2] = addMul(m.a, n.c, m.c, n.d)
+	r.d, ok[3] = addMul(m.b, n.c, m.d, n.d)
+
+	return r, ok[0] && ok[1] && ok[2] && ok[3]
+}
+
+// fits tells whether the bounds can be stored
+func (m interval) fits() bool {
+	for _, v := range []int64{m.a, m.b, m.c, m.d} {
+		if v > maxIntervalValue || v < -maxIntervalValue {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m interval) inverse() interval {
+	det := m.a*m.d - m.c*m.b
+
+	return interval{a: m.d * det, b: -m.b * det, c: -m.c * det, d: m.a * det}
+}
+
+// addMul returns x*y + z*w, false when one of the products or the sum overflows an int64
+func addMul(x, y, z, w int64) (int64, bool) {
+	xy := new(big.Int).Mul(big.NewInt(x), big.NewInt(y))
+	zw := new(big.Int).Mul(big.NewInt(z), big.NewInt(w))
+	sum := new(big.Int).Add(xy, zw)
+
+	return sum.Int64(), xy.IsInt64() && zw.IsInt64() && sum.IsInt64()
+}
+
+func getInterval(node Interface) interval {
+	return interval{
+		a: getTagInt(node, "left_num"),
+		b: getTagInt(node, "left_den"),
+		c: getTagInt(node, "right_num"),
+		d: getTagInt(node, "right_den"),
+	}
+}
+
+func (p *Plugin) insertInterval(node Interface, scope *gorm.Scope) {
+	parent := rootsInterval
+	level := 0
+	if !isRoot(node) {
+		pn, ok := findParent(node, scope)
+		if !ok {
+			panic(fmt.Errorf("parent not found: %v", node.GetParentID()))
+		}
+
+		parent = getInterval(pn)
+		level = getTreeLevel(pn) + 1
+	}
+
+	m, err := parent.child(p.nextChildIndex(parent, level, scope))
+	if err != nil {
+		scope.Err(err)
+
+		return
+	}
+
+	updateCurrentNode(node, map[string]interface{}{
+		p.leftNumName:   m.a,
+		p.leftDenName:   m.b,
+		p.rightNumName:  m.c,
+		p.rightDenName:  m.d,
+		p.treeLevelName: level,
+	}, scope)
+	recordChange(scope, Change{Kind: ChangeInserted, RowsAffected: 1})
+}
+
+// moveInterval moves the node subtree to the end of the children of parent or to
+// the end of the roots when parent is nil
+func (p *Plugin) moveInterval(node, parent Interface, scope *gorm.Scope) {
+	target := rootsInterval
+	level := 0
+	if parent != nil {
+		scope.NewDB().First(parent)
+		target = getInterval(parent)
+		level = getTreeLevel(parent) + 1
+	}
+
+	current := getInterval(node)
+	target, err := target.child(p.nextChildIndex(target, level, scope))
+	if err != nil {
+		scope.Err(err)
+
+		return
+	}
+
+	t, ok := target.mul(current.inverse())
+	if err := p.checkIntervalMove(node, t, ok, scope); err != nil {
+		scope.Err(err)
+
+		return
+	}
+
+	levelOffset := level - getTreeLevel(node)
+	where, args := p.subtreeCondition(node)
+	res := scope.DB().
+		Set(settingIgnoreUpdate, true).
+		Table(scope.TableName()).
+		Where(where, args...).
+		Updates(map[string]interface{}{
+			p.leftNumName:   gorm.Expr(p.expr("? * :left_num + ? * :left_den"), t.a, t.c),
+			p.leftDenName:   gorm.Expr(p.expr("? * :left_num + ? * :left_den"), t.b, t.d),
+			p.rightNumName:  gorm.Expr(p.expr("? * :right_num + ? * :right_den"), t.a, t.c),
+			p.rightDenName:  gorm.Expr(p.expr("? * :right_num + ? * :right_den"), t.b, t.d),
+			p.treeLevelName: gorm.Expr(p.expr(":tree_level + ?"), levelOffset),
+		})
+	recordChange(scope, Change{Kind: ChangeMoved, LevelOffset: levelOffset, RowsAffected: res.RowsAffected})
+}
+
+// checkIntervalMove returns an error when the transformation t, which overflowed when
+// not ok, gives bounds exceeding maxIntervalValue to a node of the subtree of node or
+// overflows while the UPDATE computes them
+func (p *Plugin) checkIntervalMove(node Interface, t interval, ok bool, scope *gorm.Scope) error {
+	overflow := fmt.Errorf("intervals: the bounds of the moved subtree exceed %d, the tree is too deep or too wide", maxIntervalValue)
+	if !ok {
+		return overflow
+	}
+
+	where, args := p.subtreeCondition(node)
+	rows, err := scope.NewDB().
+		Table(scope.TableName()).
+		Select(p.expr(":left_num, :left_den, :right_num, :right_den")).
+		Where(where, args...).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m interval
+		if err := rows.Scan(&m.a, &m.b, &m.c, &m.d); err != nil {
+			return err
+		}
+
+		if n, ok := t.mul(m); !ok || !n.fits() {
+			return overflow
+		}
+	}
+
+	return rows.Err()
+}
+
+// nextChildIndex returns the index of a new last child of parent
+func (p *Plugin) nextChildIndex(parent interval, level int, scope *gorm.Scope) int64 {
+	db := scope.NewDB().
+		Table(scope.TableName()).
+		Select(p.expr(":left_num, :left_den")).
+		Where(p.expr(":tree_level = ?"), level)
+	if parent != rootsInterval {
+		db = db.Where(
+			p.expr(":left_num * ? > ? * :left_den AND :left_num * ? < ? * :left_den"),
+			parent.b, parent.a, parent.d, parent.c,
+		)
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	var max int64
+	for rows.Next() {
+		var num, den int64
+		if err := rows.Scan(&num, &den); err != nil {
+			panic(err)
+		}
+
+		if k := parent.childIndex(num, den); k > max {
+			max = k
+		}
+	}
+
+	return max + 1
+}
+
+// sortByLeftBound sorts a slice of nodes by their left bound
+func sortByLeftBound(out interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(out))
+	if v.Kind() != reflect.Slice {
+		return
+	}
+
+	indexes := make([]int, v.Len())
+	bounds := make([]interval, v.Len())
+	for i := range indexes {
+		indexes[i] = i
+		bounds[i] = getInterval(sliceNode(v, i))
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		bi, bj := bounds[indexes[i]], bounds[indexes[j]]
+		l := new(big.Int).Mul(big.NewInt(bi.a), big.NewInt(bj.b))
+		r := new(big.Int).Mul(big.NewInt(bj.a), big.NewInt(bi.b))
+
+		return l.Cmp(r) < 0
+	})
+
+	sorted := reflect.MakeSlice(v.Type(), 0, v.Len())
+	for _, i := range indexes {
+		sorted = reflect.Append(sorted, v.Index(i))
+	}
+
+	v.Set(sorted)
+}
+
+func sliceNode(v reflect.Value, i int) Interface {
+	e := v.Index(i)
+	if e.Kind() == reflect.Ptr {
+		return e.Interface().(Interface)
+	}
+
+	return e.Addr().Interface().(Interface)
+}
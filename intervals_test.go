@@ -0,0 +1,215 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type Region struct {
+	ID       uint `gorm:"primary_key"`
+	Name     string
+	ParentID uint
+	Parent   *Region `gorm:"association_autoupdate:false"`
+	LeftNum  int64   `gorm-nested:"left_num"`
+	LeftDen  int64   `gorm-nested:"left_den"`
+	RightNum int64   `gorm-nested:"right_num"`
+	RightDen int64   `gorm-nested:"right_den"`
+	Level    int     `gorm-nested:"level"`
+}
+
+func (r Region) GetParentID() interface{} {
+	return r.ParentID
+}
+
+func (r Region) GetParent() nested.Interface {
+	return r.Parent
+}
+
+type IntervalsTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *IntervalsTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Region{})
+
+	suite.plugin, err = nested.Register(suite.db, nested.WithStrategy(nested.NestedIntervals))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *IntervalsTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *IntervalsTestSuite) assertBounds(name string, bounds [4]int64, level int) {
+	var region Region
+	assert.False(suite.T(), suite.db.First(&region, "name = ?", name).RecordNotFound())
+	assert.Equal(suite.T(), bounds, [4]int64{region.LeftNum, region.LeftDen, region.RightNum, region.RightDen}, name)
+	assert.Equal(suite.T(), level, region.Level, name)
+}
+
+func (suite *IntervalsTestSuite) createTree() (*Region, *Region, *Region, *Region) {
+	europe := Region{Name: "Europe"}
+	france := Region{Name: "France", Parent: &europe}
+	paris := Region{Name: "Paris", Parent: &france}
+	spain := Region{Name: "Spain", Parent: &europe}
+	suite.db.Save(&paris)
+	suite.db.Save(&spain)
+
+	return &europe, &france, &paris, &spain
+}
+
+func (suite *IntervalsTestSuite) TestInsert() {
+	suite.createTree()
+
+	asia := Region{Name: "Asia"}
+	cs, ok := nested.Changes(suite.db.Save(&asia))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []nested.Change{{Kind: nested.ChangeInserted, RowsAffected: 1}}, cs.Changes)
+
+	suite.assertBounds("Europe", [4]int64{1, 1, 2, 1}, 0)
+	suite.assertBounds("France", [4]int64{3, 2, 5, 3}, 1)
+	suite.assertBounds("Paris", [4]int64{8, 5, 13, 8}, 2)
+	suite.assertBounds("Spain", [4]int64{5, 3, 7, 4}, 1)
+	suite.assertBounds("Asia", [4]int64{2, 1, 3, 1}, 0)
+}
+
+func (suite *IntervalsTestSuite) TestMove() {
+	_, france, _, spain := suite.createTree()
+	madrid := Region{Name: "Madrid", Parent: spain}
+	suite.db.Save(&madrid)
+
+	spain.Parent = france
+	spain.ParentID = france.ID
+	suite.db.Save(spain)
+
+	suite.assertBounds("France", [4]int64{3, 2, 5, 3}, 1)
+	suite.assertBounds("Paris", [4]int64{8, 5, 13, 8}, 2)
+	suite.assertBounds("Spain", [4]int64{13, 8, 18, 11}, 2)
+	suite.assertBounds("Madrid", [4]int64{31, 19, 49, 30}, 3)
+
+	spain.Parent = nil
+	spain.ParentID = 0
+	suite.db.Save(spain)
+
+	suite.assertBounds("Spain", [4]int64{2, 1, 3, 1}, 0)
+	suite.assertBounds("Madrid", [4]int64{5, 2, 8, 3}, 1)
+}
+
+func (suite *IntervalsTestSuite) TestDelete() {
+	_, france, _, _ := suite.createTree()
+
+	suite.db.Delete(france)
+
+	var names []string
+	suite.db.Model(&Region{}).Order("id").Pluck("name", &names)
+	assert.Equal(suite.T(), []string{"Europe", "Spain"}, names)
+	suite.assertBounds("Spain", [4]int64{5, 3, 7, 4}, 1)
+}
+
+func (suite *IntervalsTestSuite) TestDescendantsAndAncestors() {
+	europe, _, paris, _ := suite.createTree()
+
+	var regions []Region
+	assert.NoError(suite.T(), suite.plugin.Descendants(europe, &regions))
+
+	var names []string
+	for _, region := range regions {
+		names = append(names, region.Name)
+	}
+	assert.Equal(suite.T(), []string{"France", "Paris", "Spain"}, names)
+
+	regions = nil
+	names = nil
+	assert.NoError(suite.T(), suite.plugin.Ancestors(paris, &regions))
+	for _, region := range regions {
+		names = append(names, region.Name)
+	}
+	assert.Equal(suite.T(), []string{"Europe", "France"}, names)
+}
+
+func (suite *IntervalsTestSuite) TestVerifyAndRebuild() {
+	_, _, paris, _ := suite.createTree()
+	suite.db.Exec("UPDATE regions SET left_num = 9, right_num = 14 WHERE id = ?", paris.ID)
+
+	problems, err := suite.plugin.Verify(&Region{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.Problem{{ID: paris.ID, Message: "bounds are not a child of the parent bounds"}}, problems)
+
+	assert.NoError(suite.T(), suite.plugin.Rebuild(&Region{}))
+	suite.assertBounds("Paris", [4]int64{8, 5, 13, 8}, 2)
+
+	problems, err = suite.plugin.Verify(&Region{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func (suite *IntervalsTestSuite) TestOverflow() {
+	parent := &Region{Name: "0"}
+	assert.NoError(suite.T(), suite.db.Save(parent).Error)
+	for level := 1; level <= 22; level++ {
+		child := &Region{Name: fmt.Sprint(level), ParentID: parent.ID, Parent: parent}
+		assert.NoError(suite.T(), suite.db.Save(child).Error)
+		parent = child
+	}
+
+	// the first children grow by about 2.6 times per level
+	suite.assertBounds("22", [4]int64{1836311903, 1134903170, 2971215073, 1836311903}, 22)
+
+	deep := Region{Name: "23", ParentID: parent.ID, Parent: parent}
+	assert.EqualError(
+		suite.T(),
+		suite.db.Save(&deep).Error,
+		"intervals: the bounds of child 1 exceed 3037000499, the tree is too deep or too wide",
+	)
+
+	var count int
+	suite.db.Model(&Region{}).Where("name = ?", "23").Count(&count)
+	assert.Equal(suite.T(), 0, count)
+
+	// the root fits under level 20 but its child does not
+	var level20 Region
+	suite.db.First(&level20, "name = ?", "20")
+	asia := Region{Name: "Asia"}
+	china := Region{Name: "China", Parent: &asia}
+	suite.db.Save(&china)
+	suite.db.First(&asia, asia.ID)
+
+	asia.ParentID = level20.ID
+	asia.Parent = &level20
+	assert.EqualError(
+		suite.T(),
+		suite.db.Save(&asia).Error,
+		"intervals: the bounds of the moved subtree exceed 3037000499, the tree is too deep or too wide",
+	)
+	suite.assertBounds("Asia", [4]int64{2, 1, 3, 1}, 0)
+	suite.assertBounds("China", [4]int64{5, 2, 8, 3}, 1)
+
+	problems, err := suite.plugin.Verify(&Region{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func TestIntervalsTestSuite(t *testing.T) {
+	suite.Run(t, new(IntervalsTestSuite))
+}
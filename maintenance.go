@@ -0,0 +1,505 @@
+package nested
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"sort"
+)
+
+// Problem is an inconsistency of the stored tree found by Verify
+type Problem struct {
+	// ID is the primary key of the node or nil when the problem is not about one node
+	ID      interface{}
+	Message string
+}
+
+type treeNode struct {
+	node     Interface
+	id       interface{}
+	parent   *treeNode
+	children []*treeNode
+	level    int
+	path     string
+}
+
+// loadedTree holds every node of a model table linked by the parent foreign keys.
+// Orphans have a parent foreign key not matching any node and cycles holds the nodes
+// not reachable from a root.
+type loadedTree struct {
+	nodes   []*treeNode
+	roots   []*treeNode
+	orphans []*treeNode
+	cycles  []*treeNode
+}
+
+// Verify checks the stored tree of model, e.g. &Taxon{}, against the parent foreign
+// keys and returns the problems found
+func (p *Plugin) Verify(model Interface) ([]Problem, error) {
+	p, end := p.observe("verify")
+	defer end()
+
+	p = p.withColumns(model)
+
+	tree, err := p.loadTree(model)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, n := range tree.orphans {
+		problems = append(problems, Problem{n.id, fmt.Sprintf("parent %v not found", n.node.GetParentID())})
+	}
+	for _, n := range tree.cycles {
+		problems = append(problems, Problem{n.id, "node is part of a parent cycle"})
+	}
+
+	for _, n := range tree.nodes {
+		problems = append(problems, p.verifyNode(n)...)
+	}
+
+	switch p.strategyOf(model) {
+	case NestedSet:
+		problems = append(problems, p.verifyNestedSet(tree)...)
+	case NestedIntervals:
+		problems = append(problems, verifyIntervals(tree)...)
+	case ClosureTable:
+		closureProblems, err := p.verifyClosure(model, tree)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, closureProblems...)
+	}
+
+	return problems, nil
+}
+
+// Verify checks the stored tree of model like Plugin.Verify without a registered plugin,
+// the strategy being guessed from the tags as for Render. opts are the options the plugin
+// is registered with, e.g. WithGap.
+func Verify(db *gorm.DB, model Interface, opts ...Option) ([]Problem, error) {
+	p := Plugin{db: db, strategy: tagStrategy(model), metrics: noopMetrics{}}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p.Verify(model)
+}
+
+// VerifyContext is Verify bound to ctx
+func (p *Plugin) VerifyContext(ctx context.Context, model Interface) ([]Problem, error) {
+	var problems []Problem
+	err := p.transaction(ctx, func(tx *Plugin) error {
+		var err error
+		problems, err = tx.Verify(model)
+
+		return err
+	})
+
+	return problems, err
+}
+
+// Rebuild recomputes the stored tree of model, e.g. &Taxon{}, from the parent foreign
+// keys. The siblings keep their current order, falling back to the primary key order.
+// Only the changed rows are updated.
+func (p *Plugin) Rebuild(model Interface) error {
+	return p.RebuildContext(context.Background(), model)
+}
+
+// RebuildContext is Rebuild bound to ctx. The rebuild runs in a transaction rolled back
+// when ctx is done.
+func (p *Plugin) RebuildContext(ctx context.Context, model Interface) error {
+	p, end := p.observe("rebuild")
+	defer end()
+
+	return p.transaction(ctx, func(tx *Plugin) error {
+		return tx.rebuild(ctx, model)
+	})
+}
+
+func (p *Plugin) rebuild(ctx context.Context, model Interface) error {
+	p = p.withColumns(model)
+
+	tree, err := p.loadTree(model)
+	if err != nil {
+		return err
+	}
+
+	if len(tree.orphans) > 0 {
+		n := tree.orphans[0]
+		return fmt.Errorf("node %v: parent %v not found", n.id, n.node.GetParentID())
+	}
+
+	if len(tree.cycles) > 0 {
+		return fmt.Errorf("node %v is part of a parent cycle", tree.cycles[0].id)
+	}
+
+	strategy := p.strategyOf(model)
+	scope := p.db.NewScope(model)
+	db := p.db.Set(settingIgnoreUpdate, true)
+
+	var walk func(nodes []*treeNode, m interval, counter *int) error
+	walk = func(nodes []*treeNode, m interval, counter *int) error {
+		for k, n := range nodes {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			want := map[string]interface{}{}
+			if strategy == NestedSet {
+				*counter++
+				want[p.treeLeftName] = int64(*counter)
+			}
+
+			bounds := m
+			if strategy == NestedIntervals {
+				var err error
+				if bounds, err = m.child(int64(k + 1)); err != nil {
+					return err
+				}
+
+				want[p.leftNumName] = bounds.a
+				want[p.leftDenName] = bounds.b
+				want[p.rightNumName] = bounds.c
+				want[p.rightDenName] = bounds.d
+			}
+
+			if err := walk(n.children, bounds, counter); err != nil {
+				return err
+			}
+
+			if strategy == NestedSet {
+				*counter++
+				want[p.treeRightName] = int64(*counter)
+			}
+			if p.treeLevelName != "" {
+				want[p.treeLevelName] = int64(n.level)
+			}
+			if p.pathName != "" {
+				want[p.pathName] = n.path
+			}
+			if p.childrenCountName != "" {
+				want[p.childrenCountName] = int64(len(n.children))
+			}
+
+			updates := p.changedColumns(n.node, want)
+			if len(updates) == 0 {
+				continue
+			}
+
+			err := db.
+				Table(scope.TableName()).
+				Where(fmt.Sprintf("%s = ?", scope.Quote(scope.PrimaryKey())), n.id).
+				Updates(updates).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	counter := 0
+	if err := walk(tree.roots, rootsInterval, &counter); err != nil {
+		return err
+	}
+
+	if strategy == ClosureTable {
+		return p.rebuildClosure(ctx, model, tree)
+	}
+
+	return nil
+}
+
+func (p *Plugin) rebuildClosure(ctx context.Context, model Interface, tree *loadedTree) error {
+	scope := p.db.NewScope(model)
+	table := p.closureTable(scope)
+	if err := exec(p.db, fmt.Sprintf("DELETE FROM %s", table)).Error; err != nil {
+		return err
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (ancestor_id, descendant_id, depth) VALUES (?, ?, ?)", table)
+	for _, n := range tree.nodes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for a, depth := n, 0; a != nil; a, depth = a.parent, depth+1 {
+			if err := exec(p.db, insert, a.id, n.id, depth).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// changedColumns returns the columns of want having another value than the node
+func (p *Plugin) changedColumns(node Interface, want map[string]interface{}) map[string]interface{} {
+	scope := p.db.NewScope(node)
+	changed := map[string]interface{}{}
+	for column, value := range want {
+		f, ok := scope.FieldByName(column)
+		if !ok {
+			continue
+		}
+
+		current := reflect.Indirect(f.Field)
+		switch current.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if current.Int() == value.(int64) {
+				continue
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if int64(current.Uint()) == value.(int64) {
+				continue
+			}
+		case reflect.String:
+			if current.String() == value.(string) {
+				continue
+			}
+		}
+
+		changed[column] = value
+	}
+
+	return changed
+}
+
+// loadTree loads every node of the model table keeping the current order of the
+// siblings, then links them by the parent foreign keys
+func (p *Plugin) loadTree(model Interface) (*loadedTree, error) {
+	scope := p.db.NewScope(model)
+	strategy := p.strategyOf(model)
+
+	db := p.db
+	if strategy == NestedSet {
+		db = db.Order(p.expr(":tree_left"))
+	}
+
+	nodes := reflect.New(reflect.SliceOf(reflect.PtrTo(scope.GetModelStruct().ModelType)))
+	if err := db.Order(scope.Quote(scope.PrimaryKey())).Find(nodes.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	if strategy == NestedIntervals {
+		sortByLeftBound(nodes.Interface())
+	}
+
+	tree := &loadedTree{}
+	byID := map[string]*treeNode{}
+	v := nodes.Elem()
+	for i := 0; i < v.Len(); i++ {
+		node := sliceNode(v, i)
+		n := &treeNode{node: node, id: p.db.NewScope(node).PrimaryKeyValue()}
+		tree.nodes = append(tree.nodes, n)
+		byID[fmt.Sprint(n.id)] = n
+	}
+
+	for _, n := range tree.nodes {
+		if isRoot(n.node) {
+			tree.roots = append(tree.roots, n)
+			continue
+		}
+
+		parent, ok := byID[fmt.Sprint(n.node.GetParentID())]
+		if !ok {
+			tree.orphans = append(tree.orphans, n)
+			continue
+		}
+
+		n.parent = parent
+		parent.children = append(parent.children, n)
+	}
+
+	reached := map[*treeNode]bool{}
+	var walk func(nodes []*treeNode, level int, path string)
+	walk = func(nodes []*treeNode, level int, path string) {
+		for _, n := range nodes {
+			reached[n] = true
+			n.level = level
+			n.path = nodePath(path, n.id)
+			walk(n.children, level+1, n.path)
+		}
+	}
+	walk(tree.roots, 0, pathSeparator)
+	walk(tree.orphans, 0, pathSeparator)
+
+	for _, n := range tree.nodes {
+		if !reached[n] {
+			tree.cycles = append(tree.cycles, n)
+		}
+	}
+
+	return tree, nil
+}
+
+// verifyNode checks the columns of the node which only depend on its position
+func (p *Plugin) verifyNode(n *treeNode) []Problem {
+	var problems []Problem
+	if p.treeLevelName != "" && getTreeLevel(n.node) != n.level {
+		problems = append(problems, Problem{n.id, fmt.Sprintf("level is %d instead of %d", getTreeLevel(n.node), n.level)})
+	}
+
+	if p.pathName != "" && getTreePath(n.node) != n.path {
+		problems = append(problems, Problem{n.id, fmt.Sprintf("path is %q instead of %q", getTreePath(n.node), n.path)})
+	}
+
+	if p.childrenCountName != "" {
+		if count := int(getTagInt(n.node, "children_count")); count != len(n.children) {
+			problems = append(problems, Problem{n.id, fmt.Sprintf("children count is %d instead of %d", count, len(n.children))})
+		}
+	}
+
+	return problems
+}
+
+func (p *Plugin) verifyNestedSet(tree *loadedTree) []Problem {
+	var problems []Problem
+	used := map[int]int{}
+	for _, n := range tree.nodes {
+		left, right := getTreeLeft(n.node), getTreeRight(n.node)
+		used[left]++
+		used[right]++
+		if left >= right {
+			problems = append(problems, Problem{n.id, fmt.Sprintf("left %d is not lower than right %d", left, right)})
+		}
+
+		if n.parent != nil && !(isInside(left, n.parent.node) && isInside(right, n.parent.node)) {
+			problems = append(problems, Problem{n.id, fmt.Sprintf("[%d, %d] is outside of the parent bounds", left, right)})
+		}
+	}
+
+	siblings := append([][]*treeNode{tree.roots}, childrenLists(tree)...)
+	for _, nodes := range siblings {
+		for i := 1; i < len(nodes); i++ {
+			prev, next := nodes[i-1], nodes[i]
+			if getTreeRight(prev.node) >= getTreeLeft(next.node) {
+				problems = append(problems, Problem{next.id, fmt.Sprintf("overlaps with sibling %v", prev.id)})
+			}
+		}
+	}
+
+	values := make([]int, 0, len(used))
+	for value := range used {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+
+	for _, value := range values {
+		if used[value] > 1 {
+			problems = append(problems, Problem{nil, fmt.Sprintf("value %d is used %d times", value, used[value])})
+		}
+	}
+
+	if p.gap == 0 {
+		for value := 1; value <= 2*len(tree.nodes); value++ {
+			if used[value] == 0 {
+				problems = append(problems, Problem{nil, fmt.Sprintf("value %d is missing", value)})
+			}
+		}
+	}
+
+	return problems
+}
+
+func isInside(value int, parent Interface) bool {
+	return value > getTreeLeft(parent) && value < getTreeRight(parent)
+}
+
+func verifyIntervals(tree *loadedTree) []Problem {
+	var problems []Problem
+	siblings := append([][]*treeNode{tree.roots}, childrenLists(tree)...)
+	for _, nodes := range siblings {
+		indexes := map[int64]interface{}{}
+		for _, n := range nodes {
+			parent := rootsInterval
+			if n.parent != nil {
+				parent = getInterval(n.parent.node)
+			}
+
+			m := getInterval(n.node)
+			k := parent.childIndex(m.a, m.b)
+			if child, err := parent.child(k); k < 1 || err != nil || child != m {
+				problems = append(problems, Problem{n.id, "bounds are not a child of the parent bounds"})
+				continue
+			}
+
+			if id, ok := indexes[k]; ok {
+				problems = append(problems, Problem{n.id, fmt.Sprintf("bounds are used by sibling %v", id)})
+			}
+
+			indexes[k] = n.id
+		}
+	}
+
+	return problems
+}
+
+func (p *Plugin) verifyClosure(model Interface, tree *loadedTree) ([]Problem, error) {
+	table := p.closureTable(p.db.NewScope(model))
+	rows, err := p.db.Raw(fmt.Sprintf("SELECT ancestor_id, descendant_id, depth FROM %s", table)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[string]int{}
+	for rows.Next() {
+		var ancestor, descendant interface{}
+		var depth int
+		if err := rows.Scan(&ancestor, &descendant, &depth); err != nil {
+			return nil, err
+		}
+
+		stored[closureKey(ancestor, descendant)] = depth
+	}
+
+	var problems []Problem
+	for _, n := range tree.nodes {
+		for a, depth := n, 0; a != nil; a, depth = a.parent, depth+1 {
+			key := closureKey(a.id, n.id)
+			d, ok := stored[key]
+			delete(stored, key)
+			switch {
+			case !ok:
+				problems = append(problems, Problem{n.id, fmt.Sprintf("closure row of ancestor %v is missing", a.id)})
+			case d != depth:
+				problems = append(problems, Problem{n.id, fmt.Sprintf("closure depth of ancestor %v is %d instead of %d", a.id, d, depth)})
+			}
+		}
+	}
+
+	if len(stored) > 0 {
+		problems = append(problems, Problem{nil, fmt.Sprintf("%d closure rows do not match the parent keys", len(stored))})
+	}
+
+	return problems, nil
+}
+
+func closureKey(ancestor, descendant interface{}) string {
+	return fmt.Sprintf("%s:%s", closureID(ancestor), closureID(descendant))
+}
+
+// closureID formats an id scanned from the closure table the same as a primary key
+func closureID(id interface{}) string {
+	if b, ok := id.([]byte); ok {
+		return string(b)
+	}
+
+	return fmt.Sprint(id)
+}
+
+func childrenLists(tree *loadedTree) [][]*treeNode {
+	var lists [][]*treeNode
+	for _, n := range tree.nodes {
+		if len(n.children) > 0 {
+			lists = append(lists, n.children)
+		}
+	}
+
+	return lists
+}
@@ -0,0 +1,200 @@
+package nested_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type MaintenanceTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *MaintenanceTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Page{}, &Folder{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *MaintenanceTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *MaintenanceTestSuite) createPages() (*Page, *Page, *Page, *Page) {
+	home := Page{Name: "Home"}
+	about := Page{Name: "About", Parent: &home}
+	team := Page{Name: "Team", Parent: &about}
+	blog := Page{Name: "Blog", Parent: &home}
+	suite.db.Save(&team)
+	suite.db.Save(&blog)
+
+	return &home, &about, &team, &blog
+}
+
+func (suite *MaintenanceTestSuite) pages() map[string]Page {
+	var pages []Page
+	suite.db.Find(&pages)
+
+	byName := map[string]Page{}
+	for _, page := range pages {
+		page.Parent = nil
+		byName[page.Name] = page
+	}
+
+	return byName
+}
+
+func (suite *MaintenanceTestSuite) TestVerifyValidTree() {
+	suite.createPages()
+
+	problems, err := suite.plugin.Verify(&Page{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func (suite *MaintenanceTestSuite) TestVerifyAndRebuild() {
+	_, _, team, _ := suite.createPages()
+	valid := suite.pages()
+
+	suite.db.Exec("UPDATE pages SET tree_left = 7, tree_level = 3, path = '/x/' WHERE id = ?", team.ID)
+
+	problems, err := suite.plugin.Verify(&Page{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.Problem{
+		{ID: team.ID, Message: "level is 3 instead of 2"},
+		{ID: team.ID, Message: `path is "/x/" instead of "/1/2/3/"`},
+		{ID: team.ID, Message: "left 7 is not lower than right 4"},
+		{ID: team.ID, Message: "[7, 4] is outside of the parent bounds"},
+		{Message: "value 7 is used 2 times"},
+		{Message: "value 3 is missing"},
+	}, problems)
+
+	assert.NoError(suite.T(), suite.plugin.Rebuild(&Page{}))
+	assert.Equal(suite.T(), valid, suite.pages())
+
+	problems, err = suite.plugin.Verify(&Page{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func (suite *MaintenanceTestSuite) TestVerifyOrphansAndCycles() {
+	_, about, team, _ := suite.createPages()
+	suite.db.Exec("UPDATE pages SET parent_id = ? WHERE id = ?", team.ID, about.ID)
+
+	problems, err := suite.plugin.Verify(&Page{})
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), problems, nested.Problem{ID: about.ID, Message: "node is part of a parent cycle"})
+	assert.Contains(suite.T(), problems, nested.Problem{ID: team.ID, Message: "node is part of a parent cycle"})
+	assert.EqualError(suite.T(), suite.plugin.Rebuild(&Page{}), fmt.Sprintf("node %d is part of a parent cycle", about.ID))
+
+	suite.db.Exec("UPDATE pages SET parent_id = 100 WHERE id = ?", about.ID)
+	problems, err = suite.plugin.Verify(&Page{})
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), problems, nested.Problem{ID: about.ID, Message: "parent 100 not found"})
+}
+
+func (suite *MaintenanceTestSuite) TestVerifyWithoutPlugin() {
+	_, _, team, _ := suite.createPages()
+	books := Category{Name: "Books"}
+	fiction := Category{Name: "Fiction", Parent: &books}
+	suite.db.Save(&fiction)
+
+	problems, err := nested.Verify(suite.db, &Category{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+
+	suite.db.Exec("UPDATE pages SET tree_level = 3 WHERE id = ?", team.ID)
+	problems, err = nested.Verify(suite.db, &Page{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.Problem{{ID: team.ID, Message: "level is 3 instead of 2"}}, problems)
+
+	suite.db.Exec("UPDATE pages SET tree_left = tree_left * 2, tree_right = tree_right * 2")
+	problems, err = nested.Verify(suite.db, &Page{}, nested.WithGap(4))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.Problem{{ID: team.ID, Message: "level is 3 instead of 2"}}, problems)
+}
+
+func (suite *MaintenanceTestSuite) TestRebuildChildrenCount() {
+	root := Folder{Name: "Root"}
+	docs := Folder{Name: "Docs", Parent: &root}
+	suite.db.Save(&docs)
+	suite.db.Exec("UPDATE folders SET children_count = 5")
+
+	problems, err := suite.plugin.Verify(&Folder{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.Problem{
+		{ID: root.ID, Message: "children count is 5 instead of 1"},
+		{ID: docs.ID, Message: "children count is 5 instead of 0"},
+	}, problems)
+
+	assert.NoError(suite.T(), suite.plugin.Rebuild(&Folder{}))
+	suite.db.First(&root)
+	suite.db.First(&docs)
+	assert.Equal(suite.T(), 1, root.ChildrenCount)
+	assert.Equal(suite.T(), 0, docs.ChildrenCount)
+}
+
+func (suite *MaintenanceTestSuite) TestRebuildClosure() {
+	books := Category{Name: "Books"}
+	fiction := Category{Name: "Fiction", Parent: &books}
+	fantasy := Category{Name: "Fantasy", Parent: &fiction}
+	suite.db.Save(&fantasy)
+
+	suite.db.Exec("DELETE FROM categories_closure WHERE descendant_id = ? AND ancestor_id = ?", fantasy.ID, books.ID)
+	suite.db.Exec("INSERT INTO categories_closure (ancestor_id, descendant_id, depth) VALUES (?, ?, 1)", fantasy.ID, books.ID)
+
+	problems, err := suite.plugin.Verify(&Category{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.Problem{
+		{ID: fantasy.ID, Message: fmt.Sprintf("closure row of ancestor %d is missing", books.ID)},
+		{Message: "1 closure rows do not match the parent keys"},
+	}, problems)
+
+	assert.NoError(suite.T(), suite.plugin.Rebuild(&Category{}))
+	problems, err = suite.plugin.Verify(&Category{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func (suite *MaintenanceTestSuite) TestRebuildCanceled() {
+	_, _, team, _ := suite.createPages()
+	suite.db.Exec("UPDATE pages SET tree_level = 5 WHERE id = ?", team.ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(suite.T(), context.Canceled, suite.plugin.RebuildContext(ctx, &Page{}))
+
+	problems, err := suite.plugin.VerifyContext(context.Background(), &Page{})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), problems, 1)
+}
+
+func TestMaintenanceTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceTestSuite))
+}
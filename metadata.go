@@ -0,0 +1,104 @@
+package nested
+
+import (
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"sync"
+)
+
+// modelMeta is the tree metadata of a model type, computed once per type instead of
+// scanning the struct fields and tags on every call
+type modelMeta struct {
+	// fields are the fields tagged with gorm-nested by tag value
+	fields map[string]reflect.StructField
+	// columns are the column names of the tagged fields by tag value
+	columns map[string]string
+	// parent is the association of the model with its parent, nil when there is none
+	parent *gorm.StructField
+}
+
+var modelMetas sync.Map
+
+// metaOf returns the metadata of the type of node, a struct or pointers to one, and nil
+// for other values
+func metaOf(node interface{}) *modelMeta {
+	t := reflect.TypeOf(node)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if m, ok := modelMetas.Load(t); ok {
+		return m.(*modelMeta)
+	}
+
+	m := &modelMeta{
+		fields:  map[string]reflect.StructField{},
+		columns: map[string]string{},
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tv, ok := f.Tag.Lookup(tagName); ok {
+			if _, ok := m.fields[tv]; !ok {
+				m.fields[tv] = f
+			}
+		}
+	}
+
+	ms := (&gorm.Scope{Value: reflect.New(t).Interface()}).GetModelStruct()
+	for _, sf := range ms.StructFields {
+		for tv, f := range m.fields {
+			if sf.Name == f.Name {
+				m.columns[tv] = sf.DBName
+			}
+		}
+
+		if isParentField(sf, t) {
+			m.parent = sf
+		}
+	}
+
+	actual, _ := modelMetas.LoadOrStore(t, m)
+
+	return actual.(*modelMeta)
+}
+
+// field returns the field tagged with tagValue
+func (m *modelMeta) field(tagValue string) (reflect.StructField, bool) {
+	if m == nil {
+		return reflect.StructField{}, false
+	}
+
+	f, ok := m.fields[tagValue]
+
+	return f, ok
+}
+
+// column returns the column of the field tagged with tagValue or an empty string
+func (m *modelMeta) column(tagValue string) string {
+	if m == nil {
+		return ""
+	}
+
+	return m.columns[tagValue]
+}
+
+// value returns the field of node tagged with tagValue
+func (m *modelMeta) value(node interface{}, tagValue string) (reflect.Value, bool) {
+	f, ok := m.field(tagValue)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	return reflect.Indirect(reflect.ValueOf(node)).FieldByIndex(f.Index), true
+}
+
+// setTagValue sets the field of node tagged with tagValue, converting value to its type
+func setTagValue(node Interface, tagValue string, value interface{}) {
+	if v, ok := metaOf(node).value(node, tagValue); ok {
+		v.Set(reflect.ValueOf(value).Convert(v.Type()))
+	}
+}
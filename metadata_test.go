@@ -0,0 +1,121 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"sync"
+	"testing"
+)
+
+type MetadataTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *MetadataTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *MetadataTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+// taxonNames returns the names of the taxons in their order
+func taxonNames(taxons []Taxon) []string {
+	names := make([]string, len(taxons))
+	for i, taxon := range taxons {
+		names[i] = taxon.Name
+	}
+
+	return names
+}
+
+// categoryNames returns the names of the categories in their order
+func categoryNames(categories []Category) []string {
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = category.Name
+	}
+
+	return names
+}
+
+func (suite *MetadataTestSuite) TestModelsSharingPlugin() {
+	taxons := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+	`)
+	categories := nestedtest.BuildTree(suite.T(), suite.db, &Category{}, `
+		Books
+		  Fiction
+		    Fantasy
+		  Poetry
+	`)
+
+	// the models have their own columns and strategies, whichever is used first
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			var descendants []Taxon
+			assert.NoError(suite.T(), suite.plugin.Descendants(taxons["Electronics"], &descendants))
+			assert.Equal(suite.T(), []string{"Television", "LCD", "Radio"}, taxonNames(descendants))
+		}()
+		go func() {
+			defer wg.Done()
+
+			var children []Category
+			assert.NoError(suite.T(), suite.plugin.Children(categories["Books"], &children))
+			assert.ElementsMatch(suite.T(), []string{"Fiction", "Poetry"}, categoryNames(children))
+		}()
+	}
+	wg.Wait()
+
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+	nestedtest.AssertValid(suite.T(), suite.db, &Category{})
+}
+
+// BenchmarkVerify reads the tree values of every node many times
+func BenchmarkVerify(b *testing.B) {
+	benchmarkEachSize(b, func(b *testing.B, t *benchmarkTree) {
+		for i := 0; i < b.N; i++ {
+			if _, err := t.plugin.Verify(&Taxon{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestMetadataTestSuite(t *testing.T) {
+	suite.Run(t, new(MetadataTestSuite))
+}
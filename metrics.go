@@ -0,0 +1,151 @@
+package nested
+
+import (
+	"github.com/jinzhu/gorm"
+	"sync"
+	"time"
+)
+
+const (
+	callbackNameMetrics = "gorm-nested:metrics"
+	settingOperation    = "gorm-nested:operation"
+)
+
+// Metrics observes the cost of the plugin operations: the callbacks, named create, update
+// and delete, and the helpers, named after them in snake case, e.g. descendants_count.
+// The statements and the rows they affected or returned include the statement saving
+// the node for the callbacks.
+type Metrics interface {
+	ObserveOperation(op string, statements int, rowsAffected int64, dur time.Duration)
+}
+
+// WithMetrics reports the operations of the plugin to metrics
+func WithMetrics(metrics Metrics) Option {
+	return func(p *Plugin) {
+		p.metrics = metrics
+	}
+}
+
+// noopMetrics is the default Metrics ignoring every operation
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveOperation(string, int, int64, time.Duration) {}
+
+// Operation is an operation observed by a MetricsCollector
+type Operation struct {
+	Name         string
+	Statements   int
+	RowsAffected int64
+	Duration     time.Duration
+}
+
+// MetricsCollector is a Metrics keeping the observed operations in memory, e.g. for tests
+type MetricsCollector struct {
+	mu         sync.Mutex
+	operations []Operation
+}
+
+// ObserveOperation records the operation
+func (c *MetricsCollector) ObserveOperation(op string, statements int, rowsAffected int64, dur time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.operations = append(c.operations, Operation{op, statements, rowsAffected, dur})
+}
+
+// Operations returns the operations observed so far
+func (c *MetricsCollector) Operations() []Operation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Operation(nil), c.operations...)
+}
+
+// Reset forgets the operations observed so far
+func (c *MetricsCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.operations = nil
+}
+
+// operation counts the statements of an operation. It is shared with the statements run
+// for it through the db settings.
+type operation struct {
+	// scope is the scope of the statement saving the node, which is counted when the
+	// operation starts
+	scope        *gorm.Scope
+	statements   int
+	rowsAffected int64
+}
+
+func (p *Plugin) hasMetrics() bool {
+	if p.metrics == nil {
+		return false
+	}
+
+	_, noop := p.metrics.(noopMetrics)
+
+	return !noop
+}
+
+// observe starts the operation named op on a copy of the plugin, unless it is already
+// part of another one, and returns it with the function reporting the operation
+func (p *Plugin) observe(op string) (*Plugin, func()) {
+	if _, ok := p.db.Get(settingOperation); ok || !p.hasMetrics() {
+		return p, func() {}
+	}
+
+	o := &operation{}
+	start := time.Now()
+
+	return p.withDB(p.db.Set(settingOperation, o)), func() {
+		p.metrics.ObserveOperation(op, o.statements, o.rowsAffected, time.Since(start))
+	}
+}
+
+// observeCallback starts the operation named op for the statement of scope, unless it is
+// already part of another one, and returns the function reporting the operation
+func (p *Plugin) observeCallback(scope *gorm.Scope, op string) func() {
+	if _, ok := scope.Get(settingOperation); ok || !p.hasMetrics() {
+		return func() {}
+	}
+
+	o := &operation{scope: scope, statements: 1, rowsAffected: scope.DB().RowsAffected}
+	scope.Set(settingOperation, o)
+	start := time.Now()
+
+	return func() {
+		p.metrics.ObserveOperation(op, o.statements, o.rowsAffected, time.Since(start))
+	}
+}
+
+// metricsCallback counts the statement of scope in the operation it is part of
+func metricsCallback(scope *gorm.Scope) {
+	if o, ok := operationOf(scope.DB()); ok && o.scope != scope {
+		o.statements++
+		o.rowsAffected += scope.DB().RowsAffected
+	}
+}
+
+// exec runs a raw statement, which has no callbacks, counting it in the operation of db
+func exec(db *gorm.DB, sql string, values ...interface{}) *gorm.DB {
+	res := db.Exec(sql, values...)
+	if o, ok := operationOf(db); ok {
+		o.statements++
+		o.rowsAffected += res.RowsAffected
+	}
+
+	return res
+}
+
+func operationOf(db *gorm.DB) (*operation, bool) {
+	v, ok := db.Get(settingOperation)
+	if !ok {
+		return nil, false
+	}
+
+	o, ok := v.(*operation)
+
+	return o, ok
+}
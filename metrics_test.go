@@ -0,0 +1,117 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	plugin    nested.Plugin
+	collector *nested.MetricsCollector
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.collector = &nested.MetricsCollector{}
+	suite.plugin, err = nested.Register(suite.db, nested.WithMetrics(suite.collector))
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *MetricsTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+// counts returns the name, statements and rows of the observed operations
+func (suite *MetricsTestSuite) counts() []string {
+	var counts []string
+	for _, op := range suite.collector.Operations() {
+		assert.True(suite.T(), op.Duration > 0)
+		counts = append(counts, fmt.Sprintf("%s %d %d", op.Name, op.Statements, op.RowsAffected))
+	}
+
+	suite.collector.Reset()
+
+	return counts
+}
+
+func (suite *MetricsTestSuite) TestCallbacks() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	suite.db.Save(&television)
+	assert.Equal(suite.T(), []string{"create 4 4", "create 7 6"}, suite.counts())
+
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&radio)
+	assert.Equal(suite.T(), []string{"create 7 6"}, suite.counts())
+
+	radio.Name = "Radios"
+	suite.db.Save(&radio)
+	assert.Equal(suite.T(), []string{"update 4 4"}, suite.counts())
+
+	suite.db.Delete(&television)
+	assert.Equal(suite.T(), []string{"delete 7 5"}, suite.counts())
+
+	// statements of the plugin only
+	suite.db.Where("name = ?", "Radios").First(&radio)
+	assert.Empty(suite.T(), suite.counts())
+}
+
+func (suite *MetricsTestSuite) TestHelpers() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	lcd := Taxon{Name: "LCD", Parent: &television}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&lcd)
+	suite.db.Save(&radio)
+	suite.collector.Reset()
+
+	var taxons []Taxon
+	assert.NoError(suite.T(), suite.plugin.Descendants(&electronics, &taxons))
+	assert.NoError(suite.T(), suite.plugin.Ancestors(&lcd, &taxons))
+	_, err := suite.plugin.DescendantsCount(&electronics)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.plugin.Move(&lcd, &radio))
+
+	// the descendants count is computed from the bounds, the move reloads the node and
+	// the parent, checks that the parent is outside of the subtree and the update
+	// callback of the move is part of it
+	assert.Equal(suite.T(), []string{"descendants 1 3", "ancestors 1 2", "descendants_count 0 0", "move 16 16"}, suite.counts())
+}
+
+func (suite *MetricsTestSuite) TestClosureTable() {
+	books := Category{Name: "Books"}
+	fiction := Category{Name: "Fiction", Parent: &books}
+	suite.db.Save(&fiction)
+
+	// the closure rows are inserted with raw statements
+	assert.Equal(suite.T(), []string{"create 4 4", "create 7 7"}, suite.counts())
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,276 @@
+// Package nestedtest helps testing code storing trees with the gorm-nested plugin.
+//
+// The trees are described by specs listing the names of the nodes one per line, the
+// children indented under their parent in their order, e.g.
+//
+//	Electronics
+//	  Television
+//	    LCD
+//	  Radio
+//	Books
+//
+// The name of a node is its first string column which is neither the primary key nor a
+// tree column, as for nested.Render.
+package nestedtest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/vcraescu/gorm-nested"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// specNode is a node of a parsed spec
+type specNode struct {
+	name     string
+	children []*specNode
+}
+
+// parseSpec parses the nodes of spec, ignoring the blank lines. The indentation of the
+// roots is the one of the first node, so that specs can be indented with the code.
+func parseSpec(spec string) ([]*specNode, error) {
+	type level struct {
+		indent int
+		node   *specNode
+	}
+
+	var roots []*specNode
+	var stack []level
+	for i, line := range strings.Split(spec, "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		dedented := false
+		for len(stack) > 0 && stack[len(stack)-1].indent > indent {
+			stack = stack[:len(stack)-1]
+			dedented = true
+		}
+
+		if len(stack) > 0 && stack[len(stack)-1].indent == indent {
+			stack = stack[:len(stack)-1]
+		} else if dedented {
+			return nil, fmt.Errorf("spec line %d: indentation of %q matches no parent", i+1, name)
+		}
+
+		n := &specNode{name: name}
+		if len(stack) == 0 {
+			roots = append(roots, n)
+		} else {
+			parent := stack[len(stack)-1].node
+			parent.children = append(parent.children, n)
+		}
+
+		stack = append(stack, level{indent, n})
+	}
+
+	return roots, nil
+}
+
+// formatSpec formats the nodes as nested.Render does with the Outline format
+func formatSpec(nodes []*specNode) string {
+	var buf bytes.Buffer
+	var walk func(nodes []*specNode, indent string)
+	walk = func(nodes []*specNode, indent string) {
+		for _, n := range nodes {
+			fmt.Fprintf(&buf, "%s%s\n", indent, n.name)
+			walk(n.children, indent+"  ")
+		}
+	}
+	walk(nodes, "")
+
+	return buf.String()
+}
+
+// BuildTree creates the nodes of spec as instances of the type of model, e.g. &Taxon{},
+// each one as the last child of its parent, and returns them reloaded by name. Among the
+// nodes sharing a name, the last one is returned.
+func BuildTree(t testing.TB, db *gorm.DB, model nested.Interface, spec string) map[string]nested.Interface {
+	t.Helper()
+
+	roots, err := parseSpec(spec)
+	if err != nil {
+		t.Fatalf("build tree: %s", err)
+
+		return nil
+	}
+
+	nameField, parentKey, err := nodeFields(db, model)
+	if err != nil {
+		t.Fatalf("build tree: %s", err)
+
+		return nil
+	}
+
+	modelType := reflect.Indirect(reflect.ValueOf(model)).Type()
+	nodes := map[string]nested.Interface{}
+	var created []nested.Interface
+	var create func([]*specNode, nested.Interface) error
+	create = func(specs []*specNode, parent nested.Interface) error {
+		for _, s := range specs {
+			v := reflect.New(modelType)
+			v.Elem().FieldByIndex(nameField).SetString(s.name)
+			if parent != nil {
+				id := reflect.ValueOf(db.NewScope(parent).PrimaryKeyValue())
+				fk := v.Elem().FieldByIndex(parentKey)
+				fk.Set(id.Convert(fk.Type()))
+			}
+
+			node := v.Interface().(nested.Interface)
+			if err := db.Create(node).Error; err != nil {
+				return fmt.Errorf("create %q: %s", s.name, err)
+			}
+
+			nodes[s.name] = node
+			created = append(created, node)
+			if err := create(s.children, node); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := create(roots, nil); err != nil {
+		t.Fatalf("build tree: %s", err)
+
+		return nil
+	}
+
+	// the tree values of the nodes created first changed with their descendants
+	for _, node := range created {
+		if err := db.First(node).Error; err != nil {
+			t.Fatalf("build tree: reload %v: %s", db.NewScope(node).PrimaryKeyValue(), err)
+
+			return nil
+		}
+	}
+
+	return nodes
+}
+
+// nodeFields returns the indexes of the name field and of the parent foreign key of model
+func nodeFields(db *gorm.DB, model nested.Interface) ([]int, []int, error) {
+	ms := db.NewScope(model).GetModelStruct()
+
+	var nameField, parentKey []int
+	for _, f := range ms.StructFields {
+		if nameField == nil && f.IsNormal && !f.IsPrimaryKey && f.Struct.Type.Kind() == reflect.String &&
+			f.Struct.Tag.Get("gorm-nested") == "" {
+			nameField = f.Struct.Index
+		}
+
+		rel := f.Relationship
+		if rel == nil || rel.Kind != "belongs_to" || len(rel.ForeignFieldNames) == 0 {
+			continue
+		}
+
+		ft := f.Struct.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft == ms.ModelType {
+			if fk, ok := ms.ModelType.FieldByName(rel.ForeignFieldNames[0]); ok {
+				parentKey = fk.Index
+			}
+		}
+	}
+
+	if nameField == nil {
+		return nil, nil, fmt.Errorf("%T has no name column", model)
+	}
+
+	if parentKey == nil {
+		return nil, nil, fmt.Errorf("%T has no parent association", model)
+	}
+
+	return nameField, parentKey, nil
+}
+
+// AssertTree asserts that the stored tree of model, e.g. &Taxon{}, has the shape of
+// expectedSpec, the siblings being in their stored order, and prints a diff of the specs
+// otherwise
+func AssertTree(t testing.TB, db *gorm.DB, model nested.Interface, expectedSpec string) bool {
+	t.Helper()
+
+	roots, err := parseSpec(expectedSpec)
+	if err != nil {
+		t.Errorf("assert tree: %s", err)
+
+		return false
+	}
+
+	var actual bytes.Buffer
+	if err := nested.Render(&actual, db, model, nested.Outline); err != nil {
+		t.Errorf("assert tree: %s", err)
+
+		return false
+	}
+
+	expected := formatSpec(roots)
+	if actual.String() == expected {
+		return true
+	}
+
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        specLines(expected),
+		B:        specLines(actual.String()),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  3,
+	})
+	t.Errorf("tree of %T differs:\n%s", model, diff)
+
+	return false
+}
+
+// specLines splits a formatted spec, each line ending with a new line
+func specLines(spec string) []string {
+	lines := strings.SplitAfter(spec, "\n")
+
+	return lines[:len(lines)-1]
+}
+
+// AssertValid asserts that nested.Verify finds no problem in the stored tree of model and
+// prints the problems with the tree otherwise. opts are the options the plugin is
+// registered with, e.g. nested.WithGap.
+func AssertValid(t testing.TB, db *gorm.DB, model nested.Interface, opts ...nested.Option) bool {
+	t.Helper()
+
+	problems, err := nested.Verify(db, model, opts...)
+	if err != nil {
+		t.Errorf("assert valid: %s", err)
+
+		return false
+	}
+
+	if len(problems) == 0 {
+		return true
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "tree of %T is not valid:\n", model)
+	for _, problem := range problems {
+		if problem.ID != nil {
+			fmt.Fprintf(&msg, "  node %v: %s\n", problem.ID, problem.Message)
+		} else {
+			fmt.Fprintf(&msg, "  %s\n", problem.Message)
+		}
+	}
+
+	msg.WriteString("tree:\n")
+	if err := nested.Render(&msg, db, model, nested.ASCII); err != nil {
+		fmt.Fprintf(&msg, "  %s\n", err)
+	}
+
+	t.Errorf("%s", msg.String())
+
+	return false
+}
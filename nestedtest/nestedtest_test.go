@@ -0,0 +1,150 @@
+package nestedtest_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+var dbName = fmt.Sprintf("test_%d.db", rand.Int())
+
+type Taxon struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *Taxon `gorm:"association_autoupdate:false"`
+	TreeLeft  int    `gorm-nested:"left"`
+	TreeRight int    `gorm-nested:"right"`
+	TreeLevel int    `gorm-nested:"level"`
+}
+
+func (t Taxon) GetParentID() interface{} {
+	return t.ParentID
+}
+
+func (t Taxon) GetParent() nested.Interface {
+	return t.Parent
+}
+
+// recorder is a testing.TB recording the failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+type NestedTestTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *NestedTestTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Taxon{})
+
+	if _, err := nested.Register(suite.db); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *NestedTestTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *NestedTestTestSuite) TestBuildTree() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		    Plasma
+		  Radio
+		Books
+	`)
+
+	assert.Len(suite.T(), nodes, 6)
+
+	electronics := nodes["Electronics"].(*Taxon)
+	assert.Equal(suite.T(), []int{1, 10, 0}, []int{electronics.TreeLeft, electronics.TreeRight, electronics.TreeLevel})
+
+	plasma := nodes["Plasma"].(*Taxon)
+	assert.Equal(suite.T(), nodes["Television"].(*Taxon).ID, plasma.ParentID)
+	assert.Equal(suite.T(), []int{5, 6, 2}, []int{plasma.TreeLeft, plasma.TreeRight, plasma.TreeLevel})
+
+	books := nodes["Books"].(*Taxon)
+	assert.Equal(suite.T(), []int{11, 12, 0}, []int{books.TreeLeft, books.TreeRight, books.TreeLevel})
+
+	assert.True(suite.T(), nestedtest.AssertValid(suite.T(), suite.db, &Taxon{}))
+}
+
+func (suite *NestedTestTestSuite) TestBuildTreeBadSpec() {
+	r := &recorder{TB: suite.T()}
+	assert.Nil(suite.T(), nestedtest.BuildTree(r, suite.db, &Taxon{}, "Electronics\n    Television\n  Radio\n"))
+	assert.Equal(suite.T(), []string{`build tree: spec line 3: indentation of "Radio" matches no parent`}, r.failures)
+}
+
+func (suite *NestedTestTestSuite) TestAssertTree() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, "Electronics\n  Television\n  Radio\n")
+	assert.True(suite.T(), nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+			Television
+			Radio
+	`))
+
+	assert.NoError(suite.T(), suite.db.Delete(nodes["Television"]).Error)
+
+	r := &recorder{TB: suite.T()}
+	assert.False(suite.T(), nestedtest.AssertTree(r, suite.db, &Taxon{}, "Electronics\n  Television\n  Radio\n"))
+	assert.Equal(suite.T(), []string{`tree of *nestedtest_test.Taxon differs:
+--- expected
++++ actual
+@@ -1,3 +1,2 @@
+ Electronics
+-  Television
+   Radio
+`}, r.failures)
+}
+
+func (suite *NestedTestTestSuite) TestAssertValid() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, "Electronics\n  Radio\n")
+	suite.db.Exec("UPDATE taxons SET tree_level = 3 WHERE id = ?", nodes["Radio"].(*Taxon).ID)
+
+	r := &recorder{TB: suite.T()}
+	assert.False(suite.T(), nestedtest.AssertValid(r, suite.db, &Taxon{}))
+	assert.Equal(suite.T(), []string{`tree of *nestedtest_test.Taxon is not valid:
+  node 2: level is 3 instead of 1
+tree:
+Electronics [1,4] 0
+└── Radio [2,3] 3
+`}, r.failures)
+}
+
+func TestNestedTestTestSuite(t *testing.T) {
+	suite.Run(t, new(NestedTestTestSuite))
+}
@@ -0,0 +1,139 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"strings"
+)
+
+// WithParentChain fills the whole Parent chain of the node saved or deleted, up to its
+// root, from a single ancestors query after every create, update and delete instead of
+// reloading the parents already set one at a time
+func WithParentChain() Option {
+	return func(p *Plugin) {
+		p.parentChain = true
+	}
+}
+
+// LoadParents fills the Parent chain of nodes, a pointer to a node or to a slice of nodes,
+// up to their roots with a single ancestors query. The nodes sharing an ancestor share
+// its instance and the parents already set are refreshed in place.
+func (p *Plugin) LoadParents(nodes interface{}) error {
+	p, end := p.observe("load_parents")
+	defer end()
+
+	return p.loadParents(nodeList(nodes))
+}
+
+func (p *Plugin) loadParents(nodes []Interface) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	p = p.withColumns(nodes[0])
+
+	var conditions []string
+	var args []interface{}
+	for _, node := range nodes {
+		if isRoot(node) {
+			continue
+		}
+
+		where, a := p.parentsCondition(node)
+		conditions = append(conditions, "("+where+")")
+		args = append(args, a...)
+	}
+
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	out := reflect.New(reflect.SliceOf(reflect.TypeOf(nodes[0])))
+	if err := p.db.Where(strings.Join(conditions, " OR "), args...).Find(out.Interface()).Error; err != nil {
+		return err
+	}
+
+	byID := map[string]Interface{}
+	for i := 0; i < out.Elem().Len(); i++ {
+		node := sliceNode(out.Elem(), i)
+		byID[fmt.Sprint(p.db.NewScope(node).PrimaryKeyValue())] = node
+	}
+
+	for _, node := range nodes {
+		// a parent cycle stops at the first node met twice
+		seen := map[Interface]bool{}
+		for cur := node; !isRoot(cur) && !seen[cur]; {
+			seen[cur] = true
+
+			id := fmt.Sprint(cur.GetParentID())
+			parent, ok := byID[id]
+			if !ok {
+				break
+			}
+
+			if current := cur.GetParent(); !isNilInterface(current) && current != parent &&
+				fmt.Sprint(p.db.NewScope(current).PrimaryKeyValue()) == id {
+				p.copyColumns(current, parent)
+				byID[id] = current
+				parent = current
+			}
+
+			if err := setParent(p.db, cur, parent); err != nil {
+				return err
+			}
+
+			cur = parent
+		}
+	}
+
+	return nil
+}
+
+// parentsCondition matches the ancestors of the node from its parent foreign key and the
+// stored values, which also holds after the node is deleted
+func (p *Plugin) parentsCondition(node Interface) (string, []interface{}) {
+	switch p.strategyOf(node) {
+	case ClosureTable:
+		scope := p.db.NewScope(node)
+
+		return fmt.Sprintf(
+			"%s IN (SELECT ancestor_id FROM %s WHERE descendant_id = ?)",
+			scope.Quote(scope.PrimaryKey()),
+			p.closureTable(scope),
+		), []interface{}{node.GetParentID()}
+	case NestedIntervals:
+		return p.ancestorsCondition(node)
+	}
+
+	// the ancestors are the nodes spanning the left value, their right value being
+	// at least the left value once the subtree of the node is deleted
+	left := getTreeLeft(node)
+
+	return p.expr(":tree_left < ? AND :tree_right >= ?"), []interface{}{left, left}
+}
+
+// copyColumns copies the column values of src to dst, leaving the associations as they are
+func (p *Plugin) copyColumns(dst, src Interface) {
+	v := reflect.Indirect(reflect.ValueOf(dst))
+	for _, f := range p.db.NewScope(src).Fields() {
+		if f.IsNormal {
+			v.FieldByIndex(f.Struct.Index).Set(f.Field)
+		}
+	}
+}
+
+// refreshNode reloads the node and its parents at the end of a callback
+func (p *Plugin) refreshNode(node Interface, scope *gorm.Scope) {
+	if !p.parentChain {
+		reloadNode(node, scope)
+
+		return
+	}
+
+	db := scope.NewDB()
+	db.First(node)
+	if err := p.withDB(db).loadParents([]Interface{node}); err != nil {
+		scope.Err(err)
+	}
+}
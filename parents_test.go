@@ -0,0 +1,147 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type ParentsTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	plugin  nested.Plugin
+	queries int
+}
+
+func (suite *ParentsTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.plugin, err = nested.Register(suite.db, nested.WithParentChain())
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+
+	suite.queries = 0
+	suite.db.Callback().Query().Register("test:count", func(*gorm.Scope) {
+		suite.queries++
+	})
+}
+
+func (suite *ParentsTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *ParentsTestSuite) createTree() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	lcd := Taxon{Name: "LCD", Parent: &television}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&lcd)
+	suite.db.Save(&radio)
+}
+
+func (suite *ParentsTestSuite) find(name string) *Taxon {
+	taxon := Taxon{}
+	suite.db.Where("name = ?", name).First(&taxon)
+
+	return &taxon
+}
+
+func (suite *ParentsTestSuite) TestLoadParents() {
+	suite.createTree()
+
+	lcd := suite.find("LCD")
+	suite.queries = 0
+	assert.NoError(suite.T(), suite.plugin.LoadParents(lcd))
+	assert.Equal(suite.T(), 1, suite.queries)
+	assert.Equal(suite.T(), "Television", lcd.Parent.Name)
+	assert.Equal(suite.T(), "Electronics", lcd.Parent.Parent.Name)
+	assert.Nil(suite.T(), lcd.Parent.Parent.Parent)
+
+	var taxons []*Taxon
+	suite.db.Where("name IN (?)", []string{"LCD", "Radio", "Electronics"}).Order("id").Find(&taxons)
+	suite.queries = 0
+	assert.NoError(suite.T(), suite.plugin.LoadParents(&taxons))
+	assert.Equal(suite.T(), 1, suite.queries)
+	assert.Nil(suite.T(), taxons[0].Parent)
+	assert.True(suite.T(), taxons[1].Parent.Parent == taxons[2].Parent)
+	assert.Equal(suite.T(), "Electronics", taxons[2].Parent.Name)
+
+	// roots need no query
+	suite.queries = 0
+	assert.NoError(suite.T(), suite.plugin.LoadParents(suite.find("Electronics")))
+	assert.Equal(suite.T(), 1, suite.queries)
+}
+
+func (suite *ParentsTestSuite) TestLoadParentsRefreshesSetParents() {
+	suite.createTree()
+
+	lcd := suite.find("LCD")
+	stale := &Taxon{ID: lcd.ParentID, Name: "stale"}
+	lcd.Parent = stale
+	assert.NoError(suite.T(), suite.plugin.LoadParents(lcd))
+	assert.True(suite.T(), stale == lcd.Parent)
+	assert.Equal(suite.T(), "Television", stale.Name)
+	assert.Equal(suite.T(), 2, stale.TreeLeft)
+	assert.Equal(suite.T(), "Electronics", stale.Parent.Name)
+}
+
+func (suite *ParentsTestSuite) TestCallbacks() {
+	suite.createTree()
+
+	television := suite.find("Television")
+	plasma := Taxon{Name: "Plasma", Parent: television}
+	suite.db.Save(&plasma)
+	assert.Equal(suite.T(), "Electronics", plasma.Parent.Parent.Name)
+	assert.Equal(suite.T(), 10, plasma.Parent.Parent.TreeRight)
+	assert.Equal(suite.T(), 7, television.TreeRight)
+
+	radio := suite.find("Radio")
+	assert.NoError(suite.T(), suite.plugin.Move(&plasma, radio))
+	assert.True(suite.T(), radio == plasma.Parent)
+	assert.Equal(suite.T(), 1, plasma.Parent.Parent.TreeLeft)
+	assert.Equal(suite.T(), 10, plasma.Parent.Parent.TreeRight)
+
+	suite.db.Delete(&plasma)
+	assert.Equal(suite.T(), []int{6, 7}, []int{radio.TreeLeft, radio.TreeRight})
+	assert.Equal(suite.T(), 8, radio.Parent.TreeRight)
+}
+
+func (suite *ParentsTestSuite) TestClosureTable() {
+	books := Category{Name: "Books"}
+	fiction := Category{Name: "Fiction", Parent: &books}
+	fantasy := Category{Name: "Fantasy", Parent: &fiction}
+	suite.db.Save(&fantasy)
+
+	loaded := Category{}
+	suite.db.Where("name = ?", "Fantasy").First(&loaded)
+	assert.NoError(suite.T(), suite.plugin.LoadParents(&loaded))
+	assert.Equal(suite.T(), "Fiction", loaded.Parent.Name)
+	assert.Equal(suite.T(), "Books", loaded.Parent.Parent.Name)
+
+	suite.db.Delete(&loaded)
+	assert.Equal(suite.T(), "Books", loaded.Parent.Parent.Name)
+}
+
+func TestParentsTestSuite(t *testing.T) {
+	suite.Run(t, new(ParentsTestSuite))
+}
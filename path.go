@@ -0,0 +1,76 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+)
+
+const pathSeparator = "/"
+
+// insertPath sets the materialized path of a new node to the path of its parent
+// followed by its primary key, e.g. /1/5/23/
+func (p *Plugin) insertPath(node Interface, scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	prefix := pathSeparator
+	if !isRoot(node) {
+		parent, ok := findParent(node, scope)
+		if !ok {
+			panic(fmt.Errorf("parent not found: %v", node.GetParentID()))
+		}
+
+		prefix = getTreePath(parent)
+	}
+
+	updateCurrentNode(node, map[string]interface{}{
+		p.pathName: nodePath(prefix, scope.PrimaryKeyValue()),
+	}, scope)
+}
+
+// movePath rewrites the path prefix of the whole node subtree in one statement
+func (p *Plugin) movePath(node, parent Interface, scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	prefix := pathSeparator
+	if parent != nil {
+		scope.NewDB().First(parent)
+		prefix = getTreePath(parent)
+	}
+
+	oldPath := getTreePath(node)
+	newPath := nodePath(prefix, scope.PrimaryKeyValue())
+	if oldPath == "" {
+		// the subtree has no path to rewrite
+		updateCurrentNode(node, map[string]interface{}{p.pathName: newPath}, scope)
+
+		return
+	}
+
+	concat := p.expr("? || SUBSTR(:path, ?)")
+	if scope.Dialect().GetName() == "mysql" {
+		concat = p.expr("CONCAT(?, SUBSTRING(:path, ?))")
+	}
+
+	scope.DB().
+		Set(settingIgnoreUpdate, true).
+		Table(scope.TableName()).
+		Where(p.expr(":path LIKE ?"), oldPath+"%").
+		Update(p.pathName, gorm.Expr(concat, newPath, len(oldPath)+1))
+}
+
+func nodePath(prefix string, id interface{}) string {
+	return fmt.Sprintf("%s%v%s", prefix, id, pathSeparator)
+}
+
+func getTreePath(node Interface) string {
+	v, ok := metaOf(node).value(node, "path")
+	if !ok {
+		return ""
+	}
+
+	return v.String()
+}
@@ -0,0 +1,116 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type Page struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *Page  `gorm:"association_autoupdate:false"`
+	Path      string `gorm-nested:"path"`
+	TreeLeft  int    `gorm-nested:"left"`
+	TreeRight int    `gorm-nested:"right"`
+	TreeLevel int    `gorm-nested:"level"`
+}
+
+func (p Page) GetParentID() interface{} {
+	return p.ParentID
+}
+
+func (p Page) GetParent() nested.Interface {
+	return p.Parent
+}
+
+type PathTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *PathTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Page{})
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (suite *PathTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *PathTestSuite) paths() map[string]string {
+	var pages []Page
+	suite.db.Find(&pages)
+
+	paths := map[string]string{}
+	for _, page := range pages {
+		paths[page.Name] = page.Path
+	}
+
+	return paths
+}
+
+func (suite *PathTestSuite) TestPath() {
+	home := Page{Name: "Home"}
+	about := Page{Name: "About", Parent: &home}
+	team := Page{Name: "Team", Parent: &about}
+	blog := Page{Name: "Blog", Parent: &home}
+	suite.db.Save(&team)
+	suite.db.Save(&blog)
+
+	assert.Equal(suite.T(), "/1/2/3/", team.Path)
+	assert.Equal(suite.T(), map[string]string{
+		"Home":  "/1/",
+		"About": "/1/2/",
+		"Team":  "/1/2/3/",
+		"Blog":  "/1/4/",
+	}, suite.paths())
+
+	assert.NoError(suite.T(), suite.plugin.Move(&about, &blog))
+	assert.Equal(suite.T(), map[string]string{
+		"Home":  "/1/",
+		"About": "/1/4/2/",
+		"Team":  "/1/4/2/3/",
+		"Blog":  "/1/4/",
+	}, suite.paths())
+
+	assert.NoError(suite.T(), suite.plugin.Move(&about, nil))
+	assert.Equal(suite.T(), map[string]string{
+		"Home":  "/1/",
+		"About": "/2/",
+		"Team":  "/2/3/",
+		"Blog":  "/1/4/",
+	}, suite.paths())
+
+	suite.db.Delete(&about)
+	assert.Equal(suite.T(), map[string]string{
+		"Home": "/1/",
+		"Blog": "/1/4/",
+	}, suite.paths())
+}
+
+func TestPathTestSuite(t *testing.T) {
+	suite.Run(t, new(PathTestSuite))
+}
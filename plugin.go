@@ -5,25 +5,81 @@ import (
 )
 
 const (
-	tagName             = "gorm-nested"
-	callbackNameCreate  = "gorm-nested:create"
-	callbackNameUpdate  = "gorm-nested:update"
-	callbackNameDelete  = "gorm-nested:delete"
-	settingIgnoreUpdate = "gorm-nested:ignore_update"
-	settingIgnoreDelete = "gorm-nested:ignore_delete"
+	tagName              = "gorm-nested"
+	callbackNameCreate   = "gorm-nested:create"
+	callbackNameUpdate   = "gorm-nested:update"
+	callbackNameDelete   = "gorm-nested:delete"
+	callbackNamePublish  = "gorm-nested:publish_changes"
+	settingIgnoreCreate  = "gorm-nested:ignore_create"
+	settingIgnoreUpdate  = "gorm-nested:ignore_update"
+	settingIgnoreDelete  = "gorm-nested:ignore_delete"
+	settingChanges       = "gorm-nested:changes"
+	settingChangesScope  = "gorm-nested:changes_scope"
+	settingSharedChanges = "gorm-nested:shared_changes"
 )
 
 // Plugin gorm nested set plugin
 type Plugin struct {
-	db            *gorm.DB
-	treeLeftName  string
-	treeRightName string
-	treeLevelName string
+	db                *gorm.DB
+	treeLeftName      string
+	treeRightName     string
+	treeLevelName     string
+	leftNumName       string
+	leftDenName       string
+	rightNumName      string
+	rightDenName      string
+	pathName          string
+	childrenCountName string
+
+	gap         int
+	strategy    Strategy
+	parentChain bool
+	metrics     Metrics
+
+	changeListeners []ChangeListener
+}
+
+// Option configures the plugin at register time
+type Option func(*Plugin)
+
+// Strategy is the encoding used to store the tree
+type Strategy int
+
+const (
+	// NestedSet stores the tree as integer left/right values
+	NestedSet Strategy = iota
+	// NestedIntervals stores the tree as rational left/right bounds in numerator/denominator
+	// columns. Inserting a node never updates other rows. The numerators and denominators
+	// grow with the depth and the index of the children and are kept under 3037000499, so
+	// that the queries multiplying two of them do not overflow an int64: a chain of first
+	// children holds 23 levels, while with 10, 100 or 1000 children at every level the last
+	// ones hold 8, 4 or 3 levels. Inserts and moves going past it fail with an error.
+	NestedIntervals
+	// ClosureTable stores every ancestor/descendant pair in a separate table named after
+	// the model table with the "_closure" suffix, created by Plugin.AutoMigrate. Moves only
+	// touch the closure rows of the moved subtree.
+	ClosureTable
+)
+
+// StrategyProvider can be implemented by the model to use another strategy than the one
+// the plugin was registered with
+type StrategyProvider interface {
+	TreeStrategy() Strategy
+}
+
+// WithStrategy selects the encoding used to store the tree
+func WithStrategy(strategy Strategy) Option {
+	return func(p *Plugin) {
+		p.strategy = strategy
+	}
 }
 
 // Register registers nested set plugin
-func Register(db *gorm.DB) (Plugin, error) {
-	p := Plugin{db: db}
+func Register(db *gorm.DB, opts ...Option) (Plugin, error) {
+	p := Plugin{db: db, metrics: noopMetrics{}}
+	for _, opt := range opts {
+		opt(&p)
+	}
 
 	p.enableCallbacks()
 
@@ -35,6 +91,15 @@ func (p *Plugin) enableCallbacks() {
 	callback.Create().After("gorm:after_create").Register(callbackNameCreate, p.createCallback)
 	callback.Update().After("gorm:after_update").Register(callbackNameUpdate, p.updateCallback)
 	callback.Delete().After("gorm:after_delete").Register(callbackNameDelete, p.deleteCallback)
+
+	callback.Create().After("gorm:commit_or_rollback_transaction").Register(callbackNameMetrics, metricsCallback)
+	callback.Update().After("gorm:commit_or_rollback_transaction").Register(callbackNameMetrics, metricsCallback)
+	callback.Delete().After("gorm:commit_or_rollback_transaction").Register(callbackNameMetrics, metricsCallback)
+	callback.Create().After("gorm:commit_or_rollback_transaction").Register(callbackNamePublish, p.publishCallback)
+	callback.Update().After("gorm:commit_or_rollback_transaction").Register(callbackNamePublish, p.publishCallback)
+	callback.Delete().After("gorm:commit_or_rollback_transaction").Register(callbackNamePublish, p.publishCallback)
+	callback.Query().After("gorm:after_query").Register(callbackNameMetrics, metricsCallback)
+	callback.RowQuery().After("gorm:row_query").Register(callbackNameMetrics, metricsCallback)
 }
 
 // Interface must be implemented by the gorm model
@@ -42,3 +107,11 @@ type Interface interface {
 	GetParentID() interface{}
 	GetParent() Interface
 }
+
+func (p *Plugin) strategyOf(node interface{}) Strategy {
+	if sp, ok := doubleToSingleIndirect(node).(StrategyProvider); ok {
+		return sp.TreeStrategy()
+	}
+
+	return p.strategy
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
 	"math/rand"
 	"os"
 	"testing"
@@ -16,7 +17,8 @@ var dbName = fmt.Sprintf("test_%d.db", rand.Int())
 
 type PluginTestSuite struct {
 	suite.Suite
-	db *gorm.DB
+	db     *gorm.DB
+	plugin nested.Plugin
 }
 
 type Taxon struct {
@@ -46,7 +48,7 @@ func (suite *PluginTestSuite) SetupTest() {
 	suite.db = db
 	suite.db.AutoMigrate(&Taxon{})
 
-	_, err = nested.Register(suite.db)
+	suite.plugin, err = nested.Register(suite.db)
 	if err != nil {
 		panic(err)
 	}
@@ -401,8 +403,8 @@ func (suite *PluginTestSuite) TestMoveNodeToRight() {
 			count++
 			break
 		case "LCD":
-			assert.Equal(suite.T(), 16, taxon.TreeLeft)
-			assert.Equal(suite.T(), 17, taxon.TreeRight)
+			assert.Equal(suite.T(), 14, taxon.TreeLeft)
+			assert.Equal(suite.T(), 15, taxon.TreeRight)
 			assert.Equal(suite.T(), 3, taxon.TreeLevel)
 			count++
 			break
@@ -621,6 +623,61 @@ func (suite *PluginTestSuite) TestAutoUpdateParentAssociation() {
 	assert.Equal(suite.T(), 3, flash.TreeLevel)
 }
 
+func (suite *PluginTestSuite) TestMoveWithStaleNodes() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+		    FM
+	`)
+
+	// the values of television and radio in memory are outdated by the first move
+	lcd, television, radio := nodes["LCD"], nodes["Television"], nodes["Radio"]
+	assert.NoError(suite.T(), suite.plugin.Move(lcd, radio))
+	assert.NoError(suite.T(), suite.plugin.Move(lcd, television))
+	assert.NoError(suite.T(), suite.plugin.Move(radio, television))
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		    Radio
+		      FM
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *PluginTestSuite) TestMoveUnderOwnSubtree() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+	`)
+
+	television, lcd := nodes["Television"].(*Taxon), nodes["LCD"].(*Taxon)
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.Move(television, lcd),
+		fmt.Sprintf("move: %d cannot be moved under its descendant %d", television.ID, lcd.ID),
+	)
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.Move(television, television),
+		fmt.Sprintf("move: %d cannot be moved under itself", television.ID),
+	)
+	assert.Error(suite.T(), suite.plugin.Move(nodes["Electronics"], lcd))
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
 func (suite *PluginTestSuite) createTree() {
 	electronics := Taxon{
 		Name: "Electronics",
@@ -759,6 +816,40 @@ func (suite *PluginTestSuite) createTree() {
 	assert.Equal(suite.T(), len(taxons), count)
 }
 
+func (suite *PluginTestSuite) TestDescendants() {
+	suite.createTree()
+
+	var portableElectronics Taxon
+	suite.db.First(&portableElectronics, "name = 'Portable Electronics'")
+
+	var taxons []Taxon
+	assert.NoError(suite.T(), suite.plugin.Descendants(&portableElectronics, &taxons))
+
+	var names []string
+	for _, taxon := range taxons {
+		names = append(names, taxon.Name)
+	}
+
+	assert.Equal(suite.T(), []string{"MP3", "Flash", "CD Player", "Radio"}, names)
+}
+
+func (suite *PluginTestSuite) TestAncestors() {
+	suite.createTree()
+
+	var flash Taxon
+	suite.db.First(&flash, "name = 'Flash'")
+
+	var taxons []*Taxon
+	assert.NoError(suite.T(), suite.plugin.Ancestors(&flash, &taxons))
+
+	var names []string
+	for _, taxon := range taxons {
+		names = append(names, taxon.Name)
+	}
+
+	assert.Equal(suite.T(), []string{"Electronics", "Portable Electronics", "MP3"}, names)
+}
+
 func (suite *PluginTestSuite) TestGetTreeLeft() {
 	t := &Taxon{
 		TreeLeft: 41,
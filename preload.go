@@ -0,0 +1,112 @@
+package nested
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PreloadChildren fills the field tagged with gorm-nested:"children", e.g. Children []*Taxon,
+// of nodes and of all their descendants. nodes is a pointer to a loaded node or to a
+// slice of them. The descendants are read with a single query on the left/right values
+// and assigned to their parents while walking them in the tree order. It requires the
+// nested set strategy.
+func (p *Plugin) PreloadChildren(nodes interface{}) error {
+	p, end := p.observe("preload_children")
+	defer end()
+
+	roots := nodeList(nodes)
+	if len(roots) == 0 {
+		return nil
+	}
+
+	p = p.withColumns(roots[0])
+
+	if p.strategyOf(roots[0]) != NestedSet {
+		return fmt.Errorf("preload: %T does not use the nested set strategy", roots[0])
+	}
+
+	f, ok := metaOf(roots[0]).field("children")
+	if !ok {
+		return fmt.Errorf("preload: %T has no children field", roots[0])
+	}
+
+	nodeType := reflect.TypeOf(roots[0])
+	if f.Type != reflect.SliceOf(nodeType) {
+		return fmt.Errorf("preload: children field %s must be a %s", f.Name, reflect.SliceOf(nodeType))
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, root := range roots {
+		conditions = append(conditions, p.expr("(:tree_left > ? AND :tree_right < ?)"))
+		args = append(args, getTreeLeft(root), getTreeRight(root))
+	}
+
+	out := reflect.New(reflect.SliceOf(nodeType))
+	err := p.db.
+		Where(strings.Join(conditions, " OR "), args...).
+		Order(p.expr(":tree_left")).
+		Find(out.Interface()).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// a root may be a descendant of another root, it is used instead of its loaded copy
+	byID := map[string]Interface{}
+	for _, root := range roots {
+		byID[fmt.Sprint(p.db.NewScope(root).PrimaryKeyValue())] = root
+	}
+
+	all := append([]Interface{}, roots...)
+	for i := 0; i < out.Elem().Len(); i++ {
+		node := sliceNode(out.Elem(), i)
+		if _, ok := byID[fmt.Sprint(p.db.NewScope(node).PrimaryKeyValue())]; !ok {
+			all = append(all, node)
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return getTreeLeft(all[i]) < getTreeLeft(all[j])
+	})
+
+	var open []Interface
+	for _, node := range all {
+		children := reflect.Indirect(reflect.ValueOf(node)).FieldByIndex(f.Index)
+		children.Set(reflect.MakeSlice(f.Type, 0, 0))
+
+		for len(open) > 0 && getTreeRight(open[len(open)-1]) < getTreeLeft(node) {
+			open = open[:len(open)-1]
+		}
+
+		if len(open) > 0 {
+			parent := reflect.Indirect(reflect.ValueOf(open[len(open)-1])).FieldByIndex(f.Index)
+			parent.Set(reflect.Append(parent, reflect.ValueOf(node)))
+		}
+
+		open = append(open, node)
+	}
+
+	return nil
+}
+
+// nodeList returns the nodes of a pointer to a node or to a slice of nodes
+func nodeList(nodes interface{}) []Interface {
+	v := reflect.Indirect(reflect.ValueOf(nodes))
+	if v.Kind() != reflect.Slice {
+		if node, ok := nodes.(Interface); ok && !isNilInterface(node) {
+			return []Interface{node}
+		}
+
+		return nil
+	}
+
+	roots := make([]Interface, v.Len())
+	for i := range roots {
+		roots[i] = sliceNode(v, i)
+	}
+
+	return roots
+}
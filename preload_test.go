@@ -0,0 +1,137 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type Section struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *Section   `gorm:"association_autoupdate:false"`
+	Children  []*Section `gorm-nested:"children"`
+	TreeLeft  int        `gorm-nested:"left"`
+	TreeRight int        `gorm-nested:"right"`
+	TreeLevel int        `gorm-nested:"level"`
+}
+
+func (s Section) GetParentID() interface{} {
+	return s.ParentID
+}
+
+func (s Section) GetParent() nested.Interface {
+	return s.Parent
+}
+
+type PreloadTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin nested.Plugin
+}
+
+func (suite *PreloadTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.plugin, err = nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Section{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *PreloadTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *PreloadTestSuite) createTree() {
+	news := Section{Name: "News"}
+	world := Section{Name: "World", Parent: &news}
+	europe := Section{Name: "Europe", Parent: &world}
+	asia := Section{Name: "Asia", Parent: &world}
+	local := Section{Name: "Local", Parent: &news}
+	sports := Section{Name: "Sports"}
+	football := Section{Name: "Football", Parent: &sports}
+	suite.db.Save(&europe)
+	suite.db.Save(&asia)
+	suite.db.Save(&local)
+	suite.db.Save(&football)
+}
+
+func childrenNames(sections []*Section) []string {
+	names := []string{}
+	for _, s := range sections {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+func (suite *PreloadTestSuite) TestPreloadNode() {
+	suite.createTree()
+
+	news := Section{}
+	suite.db.Where("name = ?", "News").First(&news)
+
+	var queries int
+	suite.db.Callback().Query().Register("test:count", func(*gorm.Scope) {
+		queries++
+	})
+	defer suite.db.Callback().Query().Remove("test:count")
+
+	assert.NoError(suite.T(), suite.plugin.PreloadChildren(&news))
+	assert.Equal(suite.T(), 1, queries)
+
+	assert.Equal(suite.T(), []string{"World", "Local"}, childrenNames(news.Children))
+	assert.Equal(suite.T(), []string{"Europe", "Asia"}, childrenNames(news.Children[0].Children))
+	assert.Empty(suite.T(), news.Children[0].Children[0].Children)
+	assert.Empty(suite.T(), news.Children[1].Children)
+}
+
+func (suite *PreloadTestSuite) TestPreloadSlice() {
+	suite.createTree()
+
+	var roots []Section
+	suite.db.Where("parent_id = 0").Order("tree_left").Find(&roots)
+	assert.NoError(suite.T(), suite.plugin.PreloadChildren(&roots))
+	assert.Equal(suite.T(), []string{"World", "Local"}, childrenNames(roots[0].Children))
+	assert.Equal(suite.T(), []string{"Football"}, childrenNames(roots[1].Children))
+
+	// a node of the slice inside another one is shared by both trees
+	var sections []*Section
+	suite.db.Where("name IN (?)", []string{"News", "World"}).Find(&sections)
+	assert.NoError(suite.T(), suite.plugin.PreloadChildren(&sections))
+	assert.True(suite.T(), sections[1] == sections[0].Children[0])
+	assert.Equal(suite.T(), []string{"Europe", "Asia"}, childrenNames(sections[1].Children))
+
+	var none []Section
+	assert.NoError(suite.T(), suite.plugin.PreloadChildren(&none))
+}
+
+func (suite *PreloadTestSuite) TestPreloadErrors() {
+	assert.EqualError(suite.T(), suite.plugin.PreloadChildren(&Category{}), "preload: *nested_test.Category does not use the nested set strategy")
+	assert.EqualError(suite.T(), suite.plugin.PreloadChildren(&Taxon{}), "preload: *nested_test.Taxon has no children field")
+}
+
+func TestPreloadTestSuite(t *testing.T) {
+	suite.Run(t, new(PreloadTestSuite))
+}
@@ -0,0 +1,200 @@
+package nested
+
+import (
+	"context"
+	"fmt"
+)
+
+// Descendants finds all the descendants of the node ordered by their position in the tree
+func (p *Plugin) Descendants(node Interface, out interface{}) error {
+	p, end := p.observe("descendants")
+	defer end()
+
+	p = p.withColumns(node)
+
+	strategy := p.strategyOf(node)
+	if strategy == ClosureTable {
+		return p.closureDescendants(node, out)
+	}
+
+	where, args := p.descendantsCondition(node)
+	if strategy == NestedIntervals {
+		if err := p.db.Where(where, args...).Find(out).Error; err != nil {
+			return err
+		}
+
+		sortByLeftBound(out)
+
+		return nil
+	}
+
+	return p.db.Where(where, args...).Order(p.expr(":tree_left")).Find(out).Error
+}
+
+// Ancestors finds all the ancestors of the node starting with the root
+func (p *Plugin) Ancestors(node Interface, out interface{}) error {
+	p, end := p.observe("ancestors")
+	defer end()
+
+	p = p.withColumns(node)
+
+	if p.strategyOf(node) == ClosureTable {
+		return p.closureAncestors(node, out)
+	}
+
+	where, args := p.ancestorsCondition(node)
+
+	return p.db.Where(where, args...).Order(p.expr(":tree_level")).Find(out).Error
+}
+
+// Children finds the direct children of the node ordered by their position in the tree
+func (p *Plugin) Children(node Interface, out interface{}) error {
+	p, end := p.observe("children")
+	defer end()
+
+	p = p.withColumns(node)
+
+	scope := p.db.NewScope(node)
+	f, ok := parentField(scope)
+	if !ok {
+		return fmt.Errorf("%T has no parent association", node)
+	}
+
+	db := p.db.Where(fmt.Sprintf("%s = ?", scope.Quote(f.Relationship.ForeignDBNames[0])), scope.PrimaryKeyValue())
+	switch p.strategyOf(node) {
+	case NestedIntervals:
+		if err := db.Find(out).Error; err != nil {
+			return err
+		}
+
+		sortByLeftBound(out)
+
+		return nil
+	case ClosureTable:
+		return db.Order(scope.Quote(scope.PrimaryKey())).Find(out).Error
+	}
+
+	return db.Order(p.expr(":tree_left")).Find(out).Error
+}
+
+// Move moves the node subtree to the end of the children of parent or to the end
+// of the roots when parent is nil. The tree values of node and parent are reloaded in
+// the transaction of the move, as earlier moves may have changed them. Moving a node
+// under itself or one of its descendants fails.
+func (p *Plugin) Move(node, parent Interface) error {
+	return p.MoveContext(context.Background(), node, parent)
+}
+
+func (p *Plugin) move(node, parent Interface) error {
+	p = p.withColumns(node)
+
+	if err := p.reloadTreeValues(node); err != nil {
+		return err
+	}
+
+	if !isNilInterface(parent) {
+		if err := p.reloadTreeValues(parent); err != nil {
+			return err
+		}
+
+		if err := p.checkNotInSubtree(node, parent); err != nil {
+			return err
+		}
+	}
+
+	if err := setParent(p.db, node, parent); err != nil {
+		return err
+	}
+
+	return p.db.Save(node).Error
+}
+
+// checkNotInSubtree fails when parent is node or one of its descendants, which would
+// detach the subtree from the tree
+func (p *Plugin) checkNotInSubtree(node, parent Interface) error {
+	scope := p.db.NewScope(node)
+	id, parentID := scope.PrimaryKeyValue(), scope.New(parent).PrimaryKeyValue()
+	if isSameNode(node, parent, scope) {
+		return fmt.Errorf("move: %v cannot be moved under itself", id)
+	}
+
+	var count int
+	where, args := p.descendantsCondition(node)
+	err := p.db.
+		Table(scope.TableName()).
+		Where(where, args...).
+		Where(fmt.Sprintf("%s = ?", scope.Quote(scope.PrimaryKey())), parentID).
+		Count(&count).
+		Error
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("move: %v cannot be moved under its descendant %v", id, parentID)
+	}
+
+	return nil
+}
+
+// treeTags are the tag values of the fields holding the stored tree values
+var treeTags = []string{"left", "right", "level", "left_num", "left_den", "right_num", "right_den", "path", "children_count"}
+
+// reloadTreeValues sets the tree values of node to the stored ones, leaving the other
+// fields as they are so that they are saved along with the move
+func (p *Plugin) reloadTreeValues(node Interface) error {
+	stored, err := p.findNode(node, p.db.NewScope(node).PrimaryKeyValue())
+	if err != nil {
+		return err
+	}
+
+	m := metaOf(node)
+	for _, tv := range treeTags {
+		if v, ok := m.value(node, tv); ok {
+			sv, _ := m.value(stored, tv)
+			v.Set(sv)
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) descendantsCondition(node Interface) (string, []interface{}) {
+	switch p.strategyOf(node) {
+	case ClosureTable:
+		return p.closureCondition(node, "descendant_id", "ancestor_id")
+	case NestedIntervals:
+		m := getInterval(node)
+
+		return p.expr(":left_num * ? > ? * :left_den AND :right_num * ? < ? * :right_den"),
+			[]interface{}{m.b, m.a, m.d, m.c}
+	}
+
+	return p.expr(":tree_left > ? AND :tree_right < ?"), []interface{}{getTreeLeft(node), getTreeRight(node)}
+}
+
+func (p *Plugin) ancestorsCondition(node Interface) (string, []interface{}) {
+	switch p.strategyOf(node) {
+	case ClosureTable:
+		return p.closureCondition(node, "ancestor_id", "descendant_id")
+	case NestedIntervals:
+		m := getInterval(node)
+
+		return p.expr(":left_num * ? < ? * :left_den AND :right_num * ? > ? * :right_den"),
+			[]interface{}{m.b, m.a, m.d, m.c}
+	}
+
+	return p.expr(":tree_left < ? AND :tree_right > ?"), []interface{}{getTreeLeft(node), getTreeRight(node)}
+}
+
+// subtreeCondition matches the node and all its descendants
+func (p *Plugin) subtreeCondition(node Interface) (string, []interface{}) {
+	if p.strategyOf(node) == NestedIntervals {
+		m := getInterval(node)
+
+		return p.expr(":left_num * ? >= ? * :left_den AND :right_num * ? <= ? * :right_den"),
+			[]interface{}{m.b, m.a, m.d, m.c}
+	}
+
+	return p.expr(":tree_left >= ? AND :tree_right <= ?"), []interface{}{getTreeLeft(node), getTreeRight(node)}
+}
@@ -0,0 +1,165 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// RenderFormat is the output of Render
+type RenderFormat string
+
+const (
+	// ASCII an indented tree annotated with the stored values, e.g. ├── LCD [5,6] 2
+	ASCII RenderFormat = "ascii"
+	// DOT a Graphviz digraph
+	DOT RenderFormat = "dot"
+	// Outline the names alone, indented by two spaces per level
+	Outline RenderFormat = "outline"
+)
+
+// Render writes the whole tree of model, e.g. &Taxon{}, as linked by the parent foreign
+// keys. The nodes are labelled with String() when the model implements fmt.Stringer or
+// with their first string column otherwise, followed by the stored tree values.
+func Render(w io.Writer, db *gorm.DB, model Interface, format RenderFormat) error {
+	p := &Plugin{db: db, strategy: tagStrategy(model)}
+	p.strategy = p.strategyOf(model)
+	p = p.withColumns(model)
+
+	tree, err := p.loadTree(model)
+	if err != nil {
+		return err
+	}
+
+	roots := append(tree.roots, tree.orphans...)
+	switch format {
+	case ASCII:
+		return p.renderASCII(w, roots, "", true)
+	case DOT:
+		return p.renderDOT(w, roots, p.db.NewScope(model).TableName())
+	case Outline:
+		return p.renderOutline(w, roots, "")
+	}
+
+	return fmt.Errorf("render: unknown format %q", format)
+}
+
+// tagStrategy guesses the strategy of the model from its tags, as Render and Verify have
+// no plugin registered with one
+func tagStrategy(model Interface) Strategy {
+	switch {
+	case hasTags(model, "left_num"):
+		return NestedIntervals
+	case hasTags(model, "left"):
+		return NestedSet
+	}
+
+	return ClosureTable
+}
+
+func (p *Plugin) renderASCII(w io.Writer, nodes []*treeNode, prefix string, top bool) error {
+	for i, n := range nodes {
+		last := i == len(nodes)-1
+		branch, indent := "├── ", "│   "
+		if last {
+			branch, indent = "└── ", "    "
+		}
+
+		if top {
+			branch, indent = "", ""
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, branch, p.renderLabel(n)); err != nil {
+			return err
+		}
+
+		if err := p.renderASCII(w, n.children, prefix+indent, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) renderOutline(w io.Writer, nodes []*treeNode, indent string) error {
+	for _, n := range nodes {
+		if _, err := fmt.Fprintf(w, "%s%s\n", indent, renderName(p.db.NewScope(n.node), n)); err != nil {
+			return err
+		}
+
+		if err := p.renderOutline(w, n.children, indent+"  "); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) renderDOT(w io.Writer, roots []*treeNode, name string) error {
+	if _, err := fmt.Fprintf(w, "digraph %s {\n", strconv.Quote(name)); err != nil {
+		return err
+	}
+
+	var walk func(nodes []*treeNode) error
+	walk = func(nodes []*treeNode) error {
+		for _, n := range nodes {
+			id := strconv.Quote(fmt.Sprint(n.id))
+			if _, err := fmt.Fprintf(w, "  %s [label=%s];\n", id, strconv.Quote(p.renderLabel(n))); err != nil {
+				return err
+			}
+
+			if n.parent != nil {
+				if _, err := fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(fmt.Sprint(n.parent.id)), id); err != nil {
+					return err
+				}
+			}
+
+			if err := walk(n.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(roots); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "}\n")
+
+	return err
+}
+
+func (p *Plugin) renderLabel(n *treeNode) string {
+	label := renderName(p.db.NewScope(n.node), n)
+	switch p.strategy {
+	case NestedSet:
+		label += fmt.Sprintf(" [%d,%d]", getTreeLeft(n.node), getTreeRight(n.node))
+	case NestedIntervals:
+		m := getInterval(n.node)
+		label += fmt.Sprintf(" [%d/%d,%d/%d]", m.a, m.b, m.c, m.d)
+	}
+
+	if p.treeLevelName != "" {
+		label += fmt.Sprintf(" %d", getTreeLevel(n.node))
+	}
+
+	return label
+}
+
+func renderName(scope *gorm.Scope, n *treeNode) string {
+	if s, ok := doubleToSingleIndirect(n.node).(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	for _, f := range scope.Fields() {
+		if f.IsNormal && !f.IsPrimaryKey && f.Field.Kind() == reflect.String && f.Tag.Get(tagName) == "" {
+			return f.Field.String()
+		}
+	}
+
+	return fmt.Sprintf("#%v", n.id)
+}
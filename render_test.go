@@ -0,0 +1,116 @@
+package nested_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type RenderTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *RenderTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	plugin, err := nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *RenderTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *RenderTestSuite) createTree() {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	lcd := Taxon{Name: "LCD", Parent: &television}
+	plasma := Taxon{Name: "Plasma", Parent: &television}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	books := Taxon{Name: "Books"}
+	suite.db.Save(&lcd)
+	suite.db.Save(&plasma)
+	suite.db.Save(&radio)
+	suite.db.Save(&books)
+}
+
+func (suite *RenderTestSuite) TestASCII() {
+	suite.createTree()
+
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), nested.Render(&buf, suite.db, &Taxon{}, nested.ASCII))
+	assert.Equal(suite.T(), `Electronics [1,10] 0
+├── Television [2,7] 1
+│   ├── LCD [3,4] 2
+│   └── Plasma [5,6] 2
+└── Radio [8,9] 1
+Books [11,12] 0
+`, buf.String())
+}
+
+func (suite *RenderTestSuite) TestDOT() {
+	suite.createTree()
+
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), nested.Render(&buf, suite.db, &Taxon{}, nested.DOT))
+	assert.Equal(suite.T(), `digraph "taxons" {
+  "1" [label="Electronics [1,10] 0"];
+  "2" [label="Television [2,7] 1"];
+  "1" -> "2";
+  "3" [label="LCD [3,4] 2"];
+  "2" -> "3";
+  "4" [label="Plasma [5,6] 2"];
+  "2" -> "4";
+  "5" [label="Radio [8,9] 1"];
+  "1" -> "5";
+  "6" [label="Books [11,12] 0"];
+}
+`, buf.String())
+}
+
+func (suite *RenderTestSuite) TestOutline() {
+	suite.createTree()
+
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), nested.Render(&buf, suite.db, &Taxon{}, nested.Outline))
+	assert.Equal(suite.T(), "Electronics\n  Television\n    LCD\n    Plasma\n  Radio\nBooks\n", buf.String())
+}
+
+func (suite *RenderTestSuite) TestClosureTable() {
+	books := Category{Name: "Books"}
+	fiction := Category{Name: "Fiction", Parent: &books}
+	suite.db.Save(&fiction)
+
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), nested.Render(&buf, suite.db, &Category{}, nested.ASCII))
+	assert.Equal(suite.T(), "Books 0\n└── Fiction 1\n", buf.String())
+
+	assert.EqualError(suite.T(), nested.Render(&buf, suite.db, &Category{}, "svg"), `render: unknown format "svg"`)
+}
+
+func TestRenderTestSuite(t *testing.T) {
+	suite.Run(t, new(RenderTestSuite))
+}
@@ -0,0 +1,63 @@
+package nested
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+)
+
+// Repo is a typed wrapper around the plugin queries. T should be a pointer to the
+// model, e.g. Repo[*Taxon], so that Move can update the node in place.
+type Repo[T Interface] struct {
+	plugin Plugin
+}
+
+// NewRepo creates a repository running the plugin queries on db
+func NewRepo[T Interface](db *gorm.DB, plugin Plugin) *Repo[T] {
+	plugin.db = db
+
+	return &Repo[T]{plugin: plugin}
+}
+
+// Descendants finds all the descendants of the node ordered by their position in the tree
+func (r *Repo[T]) Descendants(ctx context.Context, node T) ([]T, error) {
+	return r.find(ctx, node, r.plugin.DescendantsContext)
+}
+
+// Ancestors finds all the ancestors of the node starting with the root
+func (r *Repo[T]) Ancestors(ctx context.Context, node T) ([]T, error) {
+	return r.find(ctx, node, r.plugin.AncestorsContext)
+}
+
+// Children finds the direct children of the node ordered by their position in the tree
+func (r *Repo[T]) Children(ctx context.Context, node T) ([]T, error) {
+	return r.find(ctx, node, r.plugin.ChildrenContext)
+}
+
+// Root finds the root of the tree the node belongs to. A root node is its own root.
+func (r *Repo[T]) Root(ctx context.Context, node T) (T, error) {
+	ancestors, err := r.Ancestors(ctx, node)
+	if err != nil || len(ancestors) == 0 {
+		return node, err
+	}
+
+	return ancestors[0], nil
+}
+
+// Move moves the node subtree to the end of the children of parent or to the end
+// of the roots when parent is nil
+func (r *Repo[T]) Move(ctx context.Context, node, parent T) error {
+	return r.plugin.MoveContext(ctx, node, parent)
+}
+
+func (r *Repo[T]) find(
+	ctx context.Context,
+	node T,
+	query func(context.Context, Interface, interface{}) error,
+) ([]T, error) {
+	var out []T
+	if err := query(ctx, node, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package nested_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"os"
+	"testing"
+)
+
+type RepoTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo *nested.Repo[*Taxon]
+}
+
+func (suite *RepoTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Taxon{})
+
+	plugin, err := nested.Register(suite.db)
+	if err != nil {
+		panic(err)
+	}
+
+	suite.repo = nested.NewRepo[*Taxon](suite.db, plugin)
+}
+
+func (suite *RepoTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *RepoTestSuite) createTree() (*Taxon, *Taxon, *Taxon, *Taxon) {
+	electronics := Taxon{Name: "Electronics"}
+	television := Taxon{Name: "Television", Parent: &electronics}
+	lcd := Taxon{Name: "LCD", Parent: &television}
+	radio := Taxon{Name: "Radio", Parent: &electronics}
+	suite.db.Save(&lcd)
+	suite.db.Save(&radio)
+
+	return &electronics, &television, &lcd, &radio
+}
+
+func (suite *RepoTestSuite) names(taxons []*Taxon) []string {
+	var names []string
+	for _, taxon := range taxons {
+		names = append(names, taxon.Name)
+	}
+
+	return names
+}
+
+func (suite *RepoTestSuite) TestQueries() {
+	ctx := context.Background()
+	electronics, television, lcd, _ := suite.createTree()
+
+	taxons, err := suite.repo.Descendants(ctx, electronics)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"Television", "LCD", "Radio"}, suite.names(taxons))
+
+	taxons, err = suite.repo.Children(ctx, electronics)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"Television", "Radio"}, suite.names(taxons))
+
+	taxons, err = suite.repo.Ancestors(ctx, lcd)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"Electronics", "Television"}, suite.names(taxons))
+
+	root, err := suite.repo.Root(ctx, lcd)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), electronics.ID, root.ID)
+
+	root, err = suite.repo.Root(ctx, electronics)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), electronics, root)
+
+	taxons, err = suite.repo.Children(ctx, television)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"LCD"}, suite.names(taxons))
+}
+
+func (suite *RepoTestSuite) TestMove() {
+	ctx := context.Background()
+	electronics, television, _, radio := suite.createTree()
+
+	assert.NoError(suite.T(), suite.repo.Move(ctx, radio, television))
+	assert.Equal(suite.T(), television.ID, radio.ParentID)
+
+	taxons, err := suite.repo.Children(ctx, television)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"LCD", "Radio"}, suite.names(taxons))
+
+	assert.NoError(suite.T(), suite.repo.Move(ctx, television, nil))
+	suite.db.First(electronics)
+
+	taxons, err = suite.repo.Descendants(ctx, electronics)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), taxons)
+
+	root, err := suite.repo.Root(ctx, radio)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Television", root.Name)
+}
+
+func (suite *RepoTestSuite) TestCancelledContext() {
+	electronics, _, _, _ := suite.createTree()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	taxons, err := suite.repo.Descendants(ctx, electronics)
+	assert.Equal(suite.T(), context.Canceled, err)
+	assert.Nil(suite.T(), taxons)
+}
+
+func TestRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(RepoTestSuite))
+}
@@ -0,0 +1,219 @@
+package nested
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SortDirection is the order of the children sorted by SortChildrenBy
+type SortDirection string
+
+const (
+	// Ascending sorts the lowest values first
+	Ascending SortDirection = "ASC"
+	// Descending sorts the highest values first
+	Descending SortDirection = "DESC"
+)
+
+// SortOption configures SortChildren and SortChildrenBy
+type SortOption func(*sortOptions)
+
+type sortOptions struct {
+	recursive bool
+}
+
+// Recursively sorts the children of every descendant of the parent as well
+func Recursively() SortOption {
+	return func(o *sortOptions) {
+		o.recursive = true
+	}
+}
+
+// SortChildren reorders the children of parent and their subtrees so that less tells
+// whether a child comes before another one. The children comparing equal keep their
+// order. The subtrees are renumbered with a single UPDATE in a transaction. It requires
+// the nested set strategy.
+func (p *Plugin) SortChildren(parent Interface, less func(a, b Interface) bool, opts ...SortOption) error {
+	return p.SortChildrenContext(context.Background(), parent, less, opts...)
+}
+
+// SortChildrenContext is SortChildren bound to ctx
+func (p *Plugin) SortChildrenContext(
+	ctx context.Context,
+	parent Interface,
+	less func(a, b Interface) bool,
+	opts ...SortOption,
+) error {
+	p, end := p.observe("sort_children")
+	defer end()
+
+	// the changes are published once the transaction is committed
+	return p.withChanges(parent, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			return tx.sortChildren(parent, "", less, opts)
+		})
+	})
+}
+
+// SortChildrenBy reorders the children of parent and their subtrees by the value of
+// column in direction, e.g. SortChildrenBy(&taxon, "name", Ascending), as SortChildren does
+func (p *Plugin) SortChildrenBy(parent Interface, column string, direction SortDirection, opts ...SortOption) error {
+	return p.SortChildrenByContext(context.Background(), parent, column, direction, opts...)
+}
+
+// SortChildrenByContext is SortChildrenBy bound to ctx
+func (p *Plugin) SortChildrenByContext(
+	ctx context.Context,
+	parent Interface,
+	column string,
+	direction SortDirection,
+	opts ...SortOption,
+) error {
+	p, end := p.observe("sort_children")
+	defer end()
+
+	if direction != Ascending && direction != Descending {
+		return fmt.Errorf("sort: unknown direction %q", direction)
+	}
+
+	scope := p.db.NewScope(parent)
+	f, ok := scope.FieldByName(column)
+	if !ok || !f.IsNormal {
+		return fmt.Errorf("sort: unknown column %s", column)
+	}
+
+	// the changes are published once the transaction is committed
+	return p.withChanges(parent, func(p *Plugin) error {
+		return p.transaction(ctx, func(tx *Plugin) error {
+			return tx.sortChildren(parent, fmt.Sprintf("%s %s", scope.Quote(f.DBName), direction), nil, opts)
+		})
+	})
+}
+
+// sortBlock is a subtree of the sorted nodes moved by offset, along with its descendants
+// not moved by other blocks
+type sortBlock struct {
+	left, right, offset, depth int
+}
+
+// sortChildren loads the children of parent, or all its descendants when sorting
+// recursively, in order, sorts them with less and moves their subtrees in place
+func (p *Plugin) sortChildren(parent Interface, order string, less func(a, b Interface) bool, opts []SortOption) error {
+	o := sortOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	p = p.withColumns(parent)
+
+	if p.strategyOf(parent) != NestedSet {
+		return fmt.Errorf("sort: %T does not use the nested set strategy", parent)
+	}
+
+	if err := p.db.First(parent).Error; err != nil {
+		return err
+	}
+
+	scope := p.db.NewScope(parent)
+	db := p.db.Where(p.expr(":tree_left > ? AND :tree_right < ?"), getTreeLeft(parent), getTreeRight(parent))
+	if !o.recursive {
+		db = db.Where(p.expr(":tree_level = ?"), getTreeLevel(parent)+1)
+	}
+	if order != "" {
+		db = db.Order(order)
+	}
+
+	nodes := reflect.New(reflect.SliceOf(reflect.TypeOf(parent)))
+	if err := db.Order(p.expr(":tree_left")).Find(nodes.Interface()).Error; err != nil {
+		return err
+	}
+
+	children := map[string][]Interface{}
+	for i := 0; i < nodes.Elem().Len(); i++ {
+		node := sliceNode(nodes.Elem(), i)
+		id := fmt.Sprint(node.GetParentID())
+		children[id] = append(children[id], node)
+	}
+
+	var blocks []sortBlock
+	var place func(id string, shift, depth int)
+	place = func(id string, shift, depth int) {
+		nodes := children[id]
+		if len(nodes) == 0 {
+			return
+		}
+
+		// the children start where the first one did, leaving the space before it
+		cursor := getTreeLeft(nodes[0])
+		for _, node := range nodes[1:] {
+			if getTreeLeft(node) < cursor {
+				cursor = getTreeLeft(node)
+			}
+		}
+		cursor += shift
+
+		if less != nil {
+			sort.SliceStable(nodes, func(i, j int) bool {
+				return less(nodes[i], nodes[j])
+			})
+		}
+
+		for _, node := range nodes {
+			left, right := getTreeLeft(node), getTreeRight(node)
+			if offset := cursor - left; offset != shift {
+				blocks = append(blocks, sortBlock{left, right, offset, depth})
+				place(fmt.Sprint(p.db.NewScope(node).PrimaryKeyValue()), offset, depth+1)
+			} else {
+				place(fmt.Sprint(p.db.NewScope(node).PrimaryKeyValue()), shift, depth+1)
+			}
+
+			cursor += right - left + 1
+		}
+	}
+	place(fmt.Sprint(scope.PrimaryKeyValue()), 0, 0)
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	// a node takes the offset of the deepest block it is part of
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].depth > blocks[j].depth
+	})
+
+	var leftCases, rightCases strings.Builder
+	var args []interface{}
+	for _, b := range blocks {
+		leftCases.WriteString(" WHEN :tree_left BETWEEN ? AND ? THEN ?")
+		rightCases.WriteString(" WHEN :tree_right BETWEEN ? AND ? THEN ?")
+		args = append(args, b.left, b.right, b.offset)
+	}
+
+	res := p.db.
+		Set(settingIgnoreUpdate, true).
+		Table(scope.TableName()).
+		Where(p.expr(":tree_left > ? AND :tree_right < ?"), getTreeLeft(parent), getTreeRight(parent)).
+		Updates(map[string]interface{}{
+			p.treeLeftName:  gorm.Expr(p.expr(":tree_left + CASE"+leftCases.String()+" ELSE 0 END"), args...),
+			p.treeRightName: gorm.Expr(p.expr(":tree_right + CASE"+rightCases.String()+" ELSE 0 END"), args...),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	// the deepest blocks come first, as the nodes they move are not moved by the outer ones
+	for i, b := range blocks {
+		change := Change{Kind: ChangeMoved, From: b.left, To: b.right, Offset: b.offset}
+		if i == 0 {
+			change.RowsAffected = res.RowsAffected
+		}
+
+		recordChange(scope, change)
+	}
+
+	return nil
+}
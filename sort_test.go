@@ -0,0 +1,208 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"testing"
+)
+
+type SortTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	plugin    nested.Plugin
+	metrics   *nested.MetricsCollector
+	published []nested.ChangeSet
+}
+
+func (suite *SortTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.metrics = &nested.MetricsCollector{}
+	suite.plugin, err = nested.Register(
+		suite.db,
+		nested.WithMetrics(suite.metrics),
+		nested.WithChangeListener(func(cs nested.ChangeSet) {
+			suite.published = append(suite.published, cs)
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *SortTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *SortTestSuite) createTree() map[string]nested.Interface {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    Plasma
+		    LCD
+		    Tube
+		  Radio
+		  Cameras
+		    Film
+		    Digital
+		Books
+	`)
+	suite.metrics.Reset()
+	suite.published = nil
+
+	return nodes
+}
+
+// assertStatements asserts that the sort reloaded the parent, loaded the nodes and
+// updated them
+func (suite *SortTestSuite) assertStatements(statements int) {
+	operations := suite.metrics.Operations()
+	if assert.Len(suite.T(), operations, 1) {
+		assert.Equal(suite.T(), "sort_children", operations[0].Name)
+		assert.Equal(suite.T(), statements, operations[0].Statements)
+	}
+}
+
+func (suite *SortTestSuite) TestSortChildrenBy() {
+	nodes := suite.createTree()
+
+	assert.NoError(suite.T(), suite.plugin.SortChildrenBy(nodes["Electronics"], "name", nested.Ascending))
+	suite.assertStatements(3)
+	assert.Equal(suite.T(), []nested.ChangeSet{{
+		Table: "taxons",
+		Changes: []nested.Change{
+			{Kind: nested.ChangeMoved, From: 12, To: 17, Offset: -10, RowsAffected: 8},
+			{Kind: nested.ChangeMoved, From: 10, To: 11, Offset: -2},
+			{Kind: nested.ChangeMoved, From: 2, To: 9, Offset: 8},
+		},
+	}}, suite.published)
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Cameras
+		    Film
+		    Digital
+		  Radio
+		  Television
+		    Plasma
+		    LCD
+		    Tube
+		Books
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SortTestSuite) TestSortChildrenByRecursively() {
+	nodes := suite.createTree()
+
+	err := suite.plugin.SortChildrenBy(nodes["Electronics"], "Name", nested.Descending, nested.Recursively())
+	assert.NoError(suite.T(), err)
+	suite.assertStatements(3)
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    Tube
+		    Plasma
+		    LCD
+		  Radio
+		  Cameras
+		    Film
+		    Digital
+		Books
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SortTestSuite) TestSortChildren() {
+	nodes := suite.createTree()
+
+	// the longest names first, the others keeping their order
+	byLength := func(a, b nested.Interface) bool {
+		return len(a.(*Taxon).Name) > len(b.(*Taxon).Name)
+	}
+
+	assert.NoError(suite.T(), suite.plugin.SortChildren(nodes["Television"], byLength))
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    Plasma
+		    Tube
+		    LCD
+		  Radio
+		  Cameras
+		    Film
+		    Digital
+		Books
+	`)
+
+	assert.NoError(suite.T(), suite.plugin.SortChildren(nodes["Electronics"], byLength, nested.Recursively()))
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    Plasma
+		    Tube
+		    LCD
+		  Cameras
+		    Digital
+		    Film
+		  Radio
+		Books
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SortTestSuite) TestAlreadySorted() {
+	nodes := suite.createTree()
+
+	assert.NoError(suite.T(), suite.plugin.SortChildrenBy(nodes["Cameras"], "name", nested.Descending))
+	suite.assertStatements(2)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SortTestSuite) TestErrors() {
+	nodes := suite.createTree()
+
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.SortChildrenBy(nodes["Electronics"], "name; DROP TABLE taxons", nested.Ascending),
+		"sort: unknown column name; DROP TABLE taxons",
+	)
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.SortChildrenBy(nodes["Electronics"], "name", "sideways"),
+		`sort: unknown direction "sideways"`,
+	)
+
+	books := Category{Name: "Books"}
+	suite.db.Save(&books)
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.SortChildrenBy(&books, "name", nested.Ascending),
+		"sort: *nested_test.Category does not use the nested set strategy",
+	)
+}
+
+func TestSortTestSuite(t *testing.T) {
+	suite.Run(t, new(SortTestSuite))
+}
@@ -0,0 +1,173 @@
+package nested
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+)
+
+// SwapSiblings exchanges the positions of the subtrees of a and b, which must share a
+// parent, with a single UPDATE of the left/right values between them. The siblings in
+// between are shifted by the difference of the widths. a and b are reloaded before the
+// swap and hold their new values after it. It requires the nested set strategy.
+func (p *Plugin) SwapSiblings(a, b Interface) error {
+	p, end := p.observe("swap_siblings")
+	defer end()
+
+	p = p.withColumns(a)
+
+	if p.strategyOf(a) != NestedSet {
+		return fmt.Errorf("swap: %T does not use the nested set strategy", a)
+	}
+
+	return p.withChanges(a, func(p *Plugin) error {
+		if err := p.db.First(a).Error; err != nil {
+			return err
+		}
+		if err := p.db.First(b).Error; err != nil {
+			return err
+		}
+
+		return p.swapSiblings(a, b)
+	})
+}
+
+// swapSiblings swaps the loaded a and b
+func (p *Plugin) swapSiblings(a, b Interface) error {
+	scope := p.db.NewScope(a)
+	if isSameNode(a, b, scope) {
+		return nil
+	}
+
+	if fmt.Sprint(a.GetParentID()) != fmt.Sprint(b.GetParentID()) {
+		return fmt.Errorf(
+			"swap: %v and %v do not share a parent",
+			scope.PrimaryKeyValue(),
+			scope.New(b).PrimaryKeyValue(),
+		)
+	}
+
+	// x is the left subtree and y the right one
+	x, y := a, b
+	if getTreeLeft(x) > getTreeLeft(y) {
+		x, y = y, x
+	}
+
+	xl, xr, yl, yr := getTreeLeft(x), getTreeRight(x), getTreeLeft(y), getTreeRight(y)
+	if err := p.swapRanges(scope, xl, xr, yl, yr); err != nil {
+		return err
+	}
+
+	xOffset, yOffset := yr-xr, xl-yl
+	setTagValue(x, "left", xl+xOffset)
+	setTagValue(x, "right", xr+xOffset)
+	setTagValue(y, "left", yl+yOffset)
+	setTagValue(y, "right", yr+yOffset)
+
+	return nil
+}
+
+// swapRanges exchanges the left/right values in [xl, xr] with the ones in [yl, yr], on
+// its right, with a single UPDATE, shifting the values in between by the difference of
+// the widths. The ranges hold whole subtrees.
+func (p *Plugin) swapRanges(scope *gorm.Scope, xl, xr, yl, yr int) error {
+	xOffset, yOffset, betweenOffset := yr-xr, xl-yl, (yr-yl)-(xr-xl)
+
+	// each column is computed from its own value, as MySQL assigns them from left to right
+	args := []interface{}{xl, xr, xOffset, yl, yr, yOffset, betweenOffset}
+
+	res := p.db.
+		Set(settingIgnoreUpdate, true).
+		Table(scope.TableName()).
+		Where(p.expr(":tree_left >= ? AND :tree_right <= ?"), xl, yr).
+		Updates(map[string]interface{}{
+			p.treeLeftName: gorm.Expr(
+				p.expr(":tree_left + CASE WHEN :tree_left BETWEEN ? AND ? THEN ? WHEN :tree_left BETWEEN ? AND ? THEN ? ELSE ? END"),
+				args...,
+			),
+			p.treeRightName: gorm.Expr(
+				p.expr(":tree_right + CASE WHEN :tree_right BETWEEN ? AND ? THEN ? WHEN :tree_right BETWEEN ? AND ? THEN ? ELSE ? END"),
+				args...,
+			),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	recordChange(scope, Change{Kind: ChangeMoved, From: xl, To: xr, Offset: xOffset, RowsAffected: res.RowsAffected})
+	recordChange(scope, Change{Kind: ChangeMoved, From: yl, To: yr, Offset: yOffset})
+	if xr+1 < yl {
+		recordChange(scope, Change{Kind: ChangeShifted, From: xr + 1, To: yl - 1, Offset: betweenOffset})
+	}
+
+	return nil
+}
+
+// MoveUp swaps the subtree of node with the one of its previous sibling and reports
+// whether it moved, node being left as it is when it is the first child. node holds its
+// new values after the move. It requires the nested set strategy.
+func (p *Plugin) MoveUp(node Interface) (bool, error) {
+	p, end := p.observe("move_up")
+	defer end()
+
+	return p.moveBySibling(node, false)
+}
+
+// MoveDown swaps the subtree of node with the one of its next sibling and reports
+// whether it moved, node being left as it is when it is the last child. node holds its
+// new values after the move. It requires the nested set strategy.
+func (p *Plugin) MoveDown(node Interface) (bool, error) {
+	p, end := p.observe("move_down")
+	defer end()
+
+	return p.moveBySibling(node, true)
+}
+
+// moveBySibling swaps node with its previous sibling or its next one when down
+func (p *Plugin) moveBySibling(node Interface, down bool) (bool, error) {
+	p = p.withColumns(node)
+
+	if err := p.reloadNestedSetNode(node, "move"); err != nil {
+		return false, err
+	}
+
+	sibling, err := p.adjacentSibling(node, down)
+	if err != nil || sibling == nil {
+		return false, err
+	}
+
+	err = p.withChanges(node, func(p *Plugin) error {
+		return p.swapSiblings(node, sibling)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// adjacentSibling returns the previous sibling of the loaded node, or the next one when
+// down, and nil when there is none. It is the closest node of the level, which is a
+// cousin when it has another parent.
+func (p *Plugin) adjacentSibling(node Interface, down bool) (Interface, error) {
+	db := p.db.Where(p.expr(":tree_level = ?"), getTreeLevel(node))
+	if down {
+		db = db.Where(p.expr(":tree_left > ?"), getTreeRight(node)).Order(p.expr(":tree_left"))
+	} else {
+		db = db.Where(p.expr(":tree_right < ?"), getTreeLeft(node)).Order(p.expr(":tree_right desc"))
+	}
+
+	sibling := newNodePtrFromValue(node)
+	res := db.First(sibling)
+	if res.RecordNotFound() {
+		return nil, nil
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if fmt.Sprint(sibling.GetParentID()) != fmt.Sprint(node.GetParentID()) {
+		return nil, nil
+	}
+
+	return sibling, nil
+}
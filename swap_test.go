@@ -0,0 +1,317 @@
+package nested_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested"
+	"github.com/vcraescu/gorm-nested/nestedtest"
+	"os"
+	"testing"
+)
+
+type SwapTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	plugin    nested.Plugin
+	metrics   *nested.MetricsCollector
+	published []nested.ChangeSet
+}
+
+func (suite *SwapTestSuite) SetupTest() {
+	db, err := gorm.Open("sqlite3", dbName)
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+
+	suite.metrics = &nested.MetricsCollector{}
+	suite.published = nil
+	suite.plugin, err = nested.Register(
+		suite.db,
+		nested.WithMetrics(suite.metrics),
+		nested.WithChangeListener(func(cs nested.ChangeSet) {
+			suite.published = append(suite.published, cs)
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := suite.plugin.AutoMigrate(&Taxon{}, &Category{}); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *SwapTestSuite) TearDownTest() {
+	if err := suite.db.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *SwapTestSuite) swap(a, b nested.Interface) {
+	suite.metrics.Reset()
+	assert.NoError(suite.T(), suite.plugin.SwapSiblings(a, b))
+
+	// the two reloads and the update
+	operations := suite.metrics.Operations()
+	if assert.Len(suite.T(), operations, 1) {
+		assert.Equal(suite.T(), "swap_siblings", operations[0].Name)
+		assert.Equal(suite.T(), 3, operations[0].Statements)
+	}
+}
+
+func (suite *SwapTestSuite) TestAdjacent() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		    Plasma
+		  Radio
+	`)
+
+	television, radio := nodes["Television"].(*Taxon), nodes["Radio"].(*Taxon)
+	suite.swap(television, radio)
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Radio
+		  Television
+		    LCD
+		    Plasma
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+
+	assert.Equal(suite.T(), []int{2, 3}, []int{radio.TreeLeft, radio.TreeRight})
+	assert.Equal(suite.T(), []int{4, 9}, []int{television.TreeLeft, television.TreeRight})
+}
+
+func (suite *SwapTestSuite) TestWithSiblingsBetween() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Game Consoles
+		  Portable Electronics
+		    MP3
+		      Flash
+		    Radio
+		  Cameras
+	`)
+
+	suite.swap(nodes["Portable Electronics"], nodes["Television"])
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Portable Electronics
+		    MP3
+		      Flash
+		    Radio
+		  Game Consoles
+		  Television
+		    LCD
+		  Cameras
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+
+	portable := nodes["Portable Electronics"].(*Taxon)
+	assert.Equal(suite.T(), []int{2, 9, 1}, []int{portable.TreeLeft, portable.TreeRight, portable.TreeLevel})
+}
+
+func (suite *SwapTestSuite) TestChanges() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Game Consoles
+		  Portable Electronics
+		    MP3
+		      Flash
+		    Radio
+		  Cameras
+	`)
+	suite.published = nil
+
+	// a single change set once the transaction is committed
+	err := suite.plugin.SwapSiblingsContext(context.Background(), nodes["Television"], nodes["Portable Electronics"])
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []nested.ChangeSet{{
+		Table: "taxons",
+		Changes: []nested.Change{
+			{Kind: nested.ChangeMoved, From: 2, To: 5, Offset: 10, RowsAffected: 7},
+			{Kind: nested.ChangeMoved, From: 8, To: 15, Offset: -6},
+			{Kind: nested.ChangeShifted, From: 6, To: 7, Offset: 4},
+		},
+	}}, suite.published)
+
+	suite.published = nil
+	assert.Error(suite.T(), suite.plugin.SwapSiblings(nodes["LCD"], nodes["Cameras"]))
+	assert.NoError(suite.T(), suite.plugin.SwapSiblings(nodes["LCD"], nodes["LCD"]))
+	assert.Empty(suite.T(), suite.published)
+}
+
+func (suite *SwapTestSuite) TestRoots() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Books
+		Electronics
+		  Radio
+		Music
+	`)
+
+	suite.swap(nodes["Books"], nodes["Music"])
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Music
+		Electronics
+		  Radio
+		Books
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SwapTestSuite) TestNotSiblings() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		  Radio
+	`)
+
+	lcd, radio := nodes["LCD"].(*Taxon), nodes["Radio"].(*Taxon)
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.SwapSiblings(lcd, radio),
+		fmt.Sprintf("swap: %d and %d do not share a parent", lcd.ID, radio.ID),
+	)
+
+	radio.ID = 100
+	assert.Error(suite.T(), suite.plugin.SwapSiblings(lcd, radio))
+
+	assert.NoError(suite.T(), suite.plugin.SwapSiblings(lcd, lcd))
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SwapTestSuite) TestClosureTable() {
+	books := Category{Name: "Books"}
+	music := Category{Name: "Music"}
+	suite.db.Save(&books)
+	suite.db.Save(&music)
+
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.SwapSiblings(&books, &music),
+		"swap: *nested_test.Category does not use the nested set strategy",
+	)
+}
+
+func (suite *SwapTestSuite) TestMoveUpAndDown() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		    Plasma
+		  Radio
+		    FM
+		  Cameras
+		Books
+	`)
+
+	radio := nodes["Radio"].(*Taxon)
+	moved, err := suite.plugin.MoveUp(radio)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), moved)
+	assert.Equal(suite.T(), []int{2, 5}, []int{radio.TreeLeft, radio.TreeRight})
+
+	moved, err = suite.plugin.MoveUp(radio)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), moved)
+
+	suite.published = nil
+	moved, err = suite.plugin.MoveDownContext(context.Background(), nodes["Television"])
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), moved)
+	assert.Equal(suite.T(), []nested.ChangeSet{{
+		Table: "taxons",
+		Changes: []nested.Change{
+			{Kind: nested.ChangeMoved, From: 6, To: 11, Offset: 2, RowsAffected: 4},
+			{Kind: nested.ChangeMoved, From: 12, To: 13, Offset: -6},
+		},
+	}}, suite.published)
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Radio
+		    FM
+		  Cameras
+		  Television
+		    LCD
+		    Plasma
+		Books
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+}
+
+func (suite *SwapTestSuite) TestMoveAtBoundary() {
+	nodes := nestedtest.BuildTree(suite.T(), suite.db, &Taxon{}, `
+		Electronics
+		  Television
+		    LCD
+		    Plasma
+		  Radio
+		    FM
+		Books
+	`)
+
+	// the next node of the level is a cousin, found by the query following the reload
+	suite.metrics.Reset()
+	moved, err := suite.plugin.MoveDown(nodes["Plasma"])
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), moved)
+
+	operations := suite.metrics.Operations()
+	if assert.Len(suite.T(), operations, 1) {
+		assert.Equal(suite.T(), "move_down", operations[0].Name)
+		assert.Equal(suite.T(), 2, operations[0].Statements)
+	}
+
+	suite.published = nil
+	moved, err = suite.plugin.MoveUp(nodes["FM"])
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), moved)
+	assert.Empty(suite.T(), suite.published)
+
+	moved, err = suite.plugin.MoveUp(nodes["Electronics"])
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), moved)
+
+	moved, err = suite.plugin.MoveUp(nodes["Books"])
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), moved)
+
+	nestedtest.AssertTree(suite.T(), suite.db, &Taxon{}, `
+		Books
+		Electronics
+		  Television
+		    LCD
+		    Plasma
+		  Radio
+		    FM
+	`)
+	nestedtest.AssertValid(suite.T(), suite.db, &Taxon{})
+
+	books := Category{Name: "Books"}
+	suite.db.Save(&books)
+	_, err = suite.plugin.MoveUp(&books)
+	assert.EqualError(suite.T(), err, "move: *nested_test.Category does not use the nested set strategy")
+}
+
+func TestSwapTestSuite(t *testing.T) {
+	suite.Run(t, new(SwapTestSuite))
+}
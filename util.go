@@ -1,6 +1,8 @@
 package nested
 
 import (
+	"fmt"
+	"github.com/jinzhu/gorm"
 	"reflect"
 )
 
@@ -27,5 +29,65 @@ func isNilInterface(i interface{}) bool {
 		return true
 	}
 
-	return reflect.ValueOf(i).IsNil()
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// parentField returns the belongs to association of the model with itself
+func parentField(scope *gorm.Scope) (*gorm.StructField, bool) {
+	m := metaOf(scope.Value)
+	if m == nil || m.parent == nil {
+		return nil, false
+	}
+
+	return m.parent, true
+}
+
+// isParentField reports whether f is a belongs to association of the model type t with itself
+func isParentField(f *gorm.StructField, t reflect.Type) bool {
+	rel := f.Relationship
+	if rel == nil || rel.Kind != "belongs_to" || len(rel.ForeignFieldNames) == 0 {
+		return false
+	}
+
+	ft := f.Struct.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+
+	return ft == t
+}
+
+// setParent sets the parent association and the parent foreign key of the node
+func setParent(db *gorm.DB, node, parent Interface) error {
+	f, ok := parentField(db.NewScope(node))
+	if !ok {
+		return fmt.Errorf("%T has no parent association", node)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(node))
+	pv := v.FieldByIndex(f.Struct.Index)
+	fk := v.FieldByName(f.Relationship.ForeignFieldNames[0])
+	if isNilInterface(parent) {
+		pv.Set(reflect.Zero(pv.Type()))
+		fk.Set(reflect.Zero(fk.Type()))
+
+		return nil
+	}
+
+	if pv.Kind() == reflect.Ptr {
+		pv.Set(reflect.ValueOf(parent))
+	} else {
+		pv.Set(reflect.Indirect(reflect.ValueOf(parent)))
+	}
+
+	id := reflect.ValueOf(db.NewScope(parent).PrimaryKeyValue())
+	fk.Set(id.Convert(fk.Type()))
+
+	return nil
 }
@@ -0,0 +1,469 @@
+package nested
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+)
+
+// tree runs the nested set statements of a model. Inside a callback the statements
+// share the connection of the callback statement, so they are part of its transaction.
+type tree struct {
+	db     *gorm.DB
+	ctx    context.Context
+	schema *schema.Schema
+	left   *schema.Field
+	right  *schema.Field
+	level  *schema.Field
+}
+
+func newTree(db *gorm.DB, s *schema.Schema) (*tree, bool) {
+	if s == nil || s.PrioritizedPrimaryField == nil {
+		return nil, false
+	}
+
+	t := &tree{
+		db:     db.Session(&gorm.Session{NewDB: true, SkipHooks: true}),
+		ctx:    db.Statement.Context,
+		schema: s,
+		left:   fieldByTagValue(s, "left"),
+		right:  fieldByTagValue(s, "right"),
+		level:  fieldByTagValue(s, "level"),
+	}
+	if t.left == nil || t.right == nil || t.level == nil {
+		return nil, false
+	}
+
+	if t.ctx == nil {
+		t.ctx = context.Background()
+	}
+
+	return t, true
+}
+
+func (p *Plugin) createCallback(db *gorm.DB) {
+	t, ok := newTree(db, db.Statement.Schema)
+	if db.Error != nil || db.RowsAffected == 0 || !ok {
+		return
+	}
+
+	eachNode(db.Statement.ReflectValue, func(rv reflect.Value) {
+		if err := t.insert(rv); err != nil {
+			db.AddError(err)
+		}
+	})
+}
+
+func (p *Plugin) updateCallback(db *gorm.DB) {
+	t, ok := newTree(db, db.Statement.Schema)
+	if db.Error != nil || isIgnored(db, settingIgnoreUpdate) || !ok {
+		return
+	}
+
+	eachNode(db.Statement.ReflectValue, func(rv reflect.Value) {
+		if err := t.move(rv); err != nil {
+			db.AddError(err)
+		}
+	})
+}
+
+func (p *Plugin) deleteCallback(db *gorm.DB) {
+	t, ok := newTree(db, db.Statement.Schema)
+	if db.Error != nil || db.RowsAffected == 0 || isIgnored(db, settingIgnoreDelete) || !ok {
+		return
+	}
+
+	eachNode(db.Statement.ReflectValue, func(rv reflect.Value) {
+		if err := t.delete(rv); err != nil {
+			db.AddError(err)
+		}
+	})
+}
+
+// insert numbers a new node as the last root or the last child of its parent. Nodes
+// already numbered are the existing parents saved again through the association.
+func (t *tree) insert(rv reflect.Value) error {
+	node, ok := t.node(rv)
+	if !ok || t.int(t.left, rv) != 0 {
+		return nil
+	}
+
+	defer t.refresh(rv)
+
+	if isRoot(node) {
+		var max int
+		err := t.table().Select(t.expr("COALESCE(MAX(:tree_right), 0)")).Scan(&max).Error
+		if err != nil {
+			return err
+		}
+
+		return t.updateNode(rv, map[string]interface{}{
+			t.left.DBName:  max + 1,
+			t.right.DBName: max + 2,
+		})
+	}
+
+	parent, ok := t.find(node.GetParentID())
+	if !ok {
+		return fmt.Errorf("parent not found: %v", node.GetParentID())
+	}
+
+	treeRight := t.int(t.right, parent)
+	if err := t.openGap(treeRight, 2); err != nil {
+		return err
+	}
+
+	return t.updateNode(rv, map[string]interface{}{
+		t.left.DBName:  treeRight,
+		t.right.DBName: treeRight + 1,
+		t.level.DBName: t.int(t.level, parent) + 1,
+	})
+}
+
+// move moves the node subtree when its parent changed
+func (t *tree) move(rv reflect.Value) error {
+	node, ok := t.node(rv)
+	if !ok {
+		return nil
+	}
+
+	defer t.refresh(rv)
+
+	from, err := t.currentParent(rv)
+	if err != nil {
+		return err
+	}
+
+	to, err := t.newParent(node)
+	if err != nil {
+		return err
+	}
+
+	if t.isSameNode(from, to) {
+		return nil
+	}
+
+	if !to.IsValid() {
+		return t.moveToRoot(rv)
+	}
+
+	return t.moveToParent(rv, to)
+}
+
+func (t *tree) moveToRoot(rv reflect.Value) error {
+	width := t.width(rv)
+
+	var max int
+	if err := t.table().Select(t.expr("COALESCE(MAX(:tree_right), 0)")).Scan(&max).Error; err != nil {
+		return err
+	}
+
+	treeOffset := (max - width) + 1 - t.int(t.left, rv)
+	if treeOffset == 0 {
+		return nil
+	}
+
+	err := t.update(
+		t.subtree(rv),
+		map[string]interface{}{
+			t.left.DBName:  gorm.Expr(t.expr("-1 * (:tree_left + ?)"), treeOffset),
+			t.right.DBName: gorm.Expr(t.expr("-1 * (:tree_right + ?)"), treeOffset),
+			t.level.DBName: gorm.Expr(t.expr(":tree_level + ?"), 0-t.int(t.level, rv)),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := t.shiftTreeFromRightOf(rv, width); err != nil {
+		return err
+	}
+
+	return t.putBack()
+}
+
+func (t *tree) moveToParent(rv, parent reflect.Value) error {
+	width := t.width(rv)
+
+	// the parent right value moves to the left when the node is removed from its left side
+	parentRight := t.int(t.right, parent)
+	if parentRight > t.int(t.right, rv) {
+		parentRight -= width
+	}
+
+	treeOffset := parentRight - t.int(t.left, rv)
+	levelOffset := t.int(t.level, parent) + 1 - t.int(t.level, rv)
+
+	// detach the node subtree by negating its values
+	err := t.update(
+		t.subtree(rv),
+		map[string]interface{}{
+			t.left.DBName:  gorm.Expr(t.expr("0 - (:tree_left + ?)"), treeOffset),
+			t.right.DBName: gorm.Expr(t.expr("0 - (:tree_right + ?)"), treeOffset),
+			t.level.DBName: gorm.Expr(t.expr(":tree_level + ?"), levelOffset),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := t.shiftTreeFromRightOf(rv, width); err != nil {
+		return err
+	}
+
+	// reload parent because it might be updated by the query from above
+	if err := t.reload(parent); err != nil {
+		return err
+	}
+
+	if err := t.shiftTreeFromRightOf(parent, -1*width); err != nil {
+		return err
+	}
+
+	err = t.updateNode(parent, map[string]interface{}{
+		t.right.DBName: t.int(t.right, parent) + width,
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.putBack()
+}
+
+// delete removes the descendants of a deleted node and closes the gap it left
+func (t *tree) delete(rv reflect.Value) error {
+	if _, ok := t.node(rv); !ok {
+		return nil
+	}
+
+	err := t.db.
+		Set(settingIgnoreDelete, true).
+		Where(t.expr(":tree_left > ? AND :tree_right < ?"), t.int(t.left, rv), t.int(t.right, rv)).
+		Delete(reflect.New(t.schema.ModelType).Interface()).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return t.shiftTreeFromRightOf(rv, t.width(rv))
+}
+
+func (t *tree) shiftTreeFromRightOf(rv reflect.Value, offset int) error {
+	treeRight := t.int(t.right, rv)
+	err := t.update(
+		t.table().Where(t.expr(":tree_right > ?"), treeRight),
+		map[string]interface{}{t.right.DBName: gorm.Expr(t.expr(":tree_right - ?"), offset)},
+	)
+	if err != nil {
+		return err
+	}
+
+	return t.update(
+		t.table().Where(t.expr(":tree_left > ?"), treeRight),
+		map[string]interface{}{t.left.DBName: gorm.Expr(t.expr(":tree_left - ?"), offset)},
+	)
+}
+
+// openGap shifts every left/right value greater or equal than treeValue by offset
+func (t *tree) openGap(treeValue, offset int) error {
+	err := t.update(
+		t.table().Where(t.expr(":tree_right >= ?"), treeValue),
+		map[string]interface{}{t.right.DBName: gorm.Expr(t.expr(":tree_right + ?"), offset)},
+	)
+	if err != nil {
+		return err
+	}
+
+	return t.update(
+		t.table().Where(t.expr(":tree_left >= ?"), treeValue),
+		map[string]interface{}{t.left.DBName: gorm.Expr(t.expr(":tree_left + ?"), offset)},
+	)
+}
+
+// putBack restores the detached subtree
+func (t *tree) putBack() error {
+	return t.update(
+		t.table().Where(t.expr(":tree_right < 0")),
+		map[string]interface{}{
+			t.left.DBName:  gorm.Expr(t.expr("-1 * :tree_left")),
+			t.right.DBName: gorm.Expr(t.expr("-1 * :tree_right")),
+		},
+	)
+}
+
+// currentParent finds the parent of the node as it is stored in the tree, before
+// the node is moved
+func (t *tree) currentParent(rv reflect.Value) (reflect.Value, error) {
+	if t.int(t.level, rv) == 0 {
+		return reflect.Value{}, nil
+	}
+
+	parent := reflect.New(t.schema.ModelType)
+	res := t.db.
+		Where(t.expr(":tree_left < ? AND :tree_right > ?"), t.int(t.left, rv), t.int(t.right, rv)).
+		Where(t.expr(":tree_level = ?"), t.int(t.level, rv)-1).
+		Limit(1).
+		Find(parent.Interface())
+	if res.Error != nil || res.RowsAffected == 0 {
+		return reflect.Value{}, res.Error
+	}
+
+	return parent.Elem(), nil
+}
+
+// newParent returns the parent the node is being moved to or an invalid value if the
+// node becomes a root
+func (t *tree) newParent(node Interface) (reflect.Value, error) {
+	if isRoot(node) {
+		return reflect.Value{}, nil
+	}
+
+	if parent := node.GetParent(); !isNilInterface(parent) {
+		return reflect.Indirect(reflect.ValueOf(parent)), nil
+	}
+
+	parent, ok := t.find(node.GetParentID())
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("parent not found: %v", node.GetParentID())
+	}
+
+	return parent, nil
+}
+
+func (t *tree) isSameNode(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+
+	return fmt.Sprint(t.id(a)) == fmt.Sprint(t.id(b))
+}
+
+// node returns the node stored in rv or false when rv is not a saved tree node
+func (t *tree) node(rv reflect.Value) (Interface, bool) {
+	if !rv.CanAddr() {
+		return nil, false
+	}
+
+	if _, zero := t.schema.PrioritizedPrimaryField.ValueOf(t.ctx, rv); zero {
+		return nil, false
+	}
+
+	node, ok := rv.Addr().Interface().(Interface)
+
+	return node, ok
+}
+
+func (t *tree) find(id interface{}) (reflect.Value, bool) {
+	parent := reflect.New(t.schema.ModelType)
+	res := t.db.Where(t.pkCondition(), id).Limit(1).Find(parent.Interface())
+	if res.Error != nil || res.RowsAffected == 0 {
+		return reflect.Value{}, false
+	}
+
+	return parent.Elem(), true
+}
+
+// refresh reloads the node and the parents set on it
+func (t *tree) refresh(rv reflect.Value) {
+	node, ok := t.node(rv)
+	if !ok {
+		return
+	}
+
+	parent := node.GetParent()
+	for !isNilInterface(parent) {
+		t.reload(reflect.Indirect(reflect.ValueOf(parent)))
+		parent = parent.GetParent()
+	}
+
+	t.reload(rv)
+}
+
+func (t *tree) reload(rv reflect.Value) error {
+	return t.db.Where(t.pkCondition(), t.id(rv)).Take(rv.Addr().Interface()).Error
+}
+
+func (t *tree) updateNode(rv reflect.Value, updates map[string]interface{}) error {
+	return t.update(t.table().Where(t.pkCondition(), t.id(rv)), updates)
+}
+
+func (t *tree) update(db *gorm.DB, updates map[string]interface{}) error {
+	return db.Set(settingIgnoreUpdate, true).Updates(updates).Error
+}
+
+func (t *tree) subtree(rv reflect.Value) *gorm.DB {
+	return t.table().Where(t.expr(":tree_left >= ? AND :tree_right <= ?"), t.int(t.left, rv), t.int(t.right, rv))
+}
+
+func (t *tree) table() *gorm.DB {
+	return t.db.Table(t.schema.Table)
+}
+
+func (t *tree) pkCondition() string {
+	return fmt.Sprintf("%s = ?", t.db.Statement.Quote(t.schema.PrioritizedPrimaryField.DBName))
+}
+
+func (t *tree) expr(expr string) string {
+	expr = strings.Replace(expr, ":tree_left", t.db.Statement.Quote(t.left.DBName), -1)
+	expr = strings.Replace(expr, ":tree_right", t.db.Statement.Quote(t.right.DBName), -1)
+	expr = strings.Replace(expr, ":tree_level", t.db.Statement.Quote(t.level.DBName), -1)
+
+	return expr
+}
+
+func (t *tree) id(rv reflect.Value) interface{} {
+	id, _ := t.schema.PrioritizedPrimaryField.ValueOf(t.ctx, rv)
+
+	return id
+}
+
+func (t *tree) int(f *schema.Field, rv reflect.Value) int {
+	return int(reflect.Indirect(f.ReflectValueOf(t.ctx, rv)).Int())
+}
+
+func (t *tree) width(rv reflect.Value) int {
+	return t.int(t.right, rv) - t.int(t.left, rv) + 1
+}
+
+// eachNode calls fn with every struct saved by a statement
+func eachNode(rv reflect.Value, fn func(reflect.Value)) {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Struct:
+		fn(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if e := reflect.Indirect(rv.Index(i)); e.Kind() == reflect.Struct {
+				fn(e)
+			}
+		}
+	}
+}
+
+func fieldByTagValue(s *schema.Schema, tagValue string) *schema.Field {
+	for _, f := range s.Fields {
+		if f.Tag.Get(tagName) == tagValue && f.DBName != "" {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func isIgnored(db *gorm.DB, setting string) bool {
+	v, ok := db.Get(setting)
+	if !ok {
+		return false
+	}
+
+	vv, _ := v.(bool)
+
+	return vv
+}
+
+func isRoot(node Interface) bool {
+	return isZeroValue(node.GetParentID())
+}
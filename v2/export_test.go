@@ -0,0 +1,7 @@
+package nested
+
+var (
+	GetTreeLeft  = getTreeLeft
+	GetTreeRight = getTreeRight
+	GetTreeLevel = getTreeLevel
+)
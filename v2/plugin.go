@@ -0,0 +1,52 @@
+package nested
+
+import (
+	"gorm.io/gorm"
+)
+
+const (
+	pluginName          = "gorm-nested"
+	tagName             = "gorm-nested"
+	callbackNameCreate  = "gorm-nested:create"
+	callbackNameUpdate  = "gorm-nested:update"
+	callbackNameDelete  = "gorm-nested:delete"
+	settingIgnoreUpdate = "gorm-nested:ignore_update"
+	settingIgnoreDelete = "gorm-nested:ignore_delete"
+)
+
+// Plugin gorm nested set plugin
+type Plugin struct {
+	db *gorm.DB
+}
+
+// Interface must be implemented by the gorm model
+type Interface interface {
+	GetParentID() interface{}
+	GetParent() Interface
+}
+
+// New creates the plugin, to be registered with db.Use
+func New() *Plugin {
+	return &Plugin{}
+}
+
+// Name implements gorm.Plugin
+func (p *Plugin) Name() string {
+	return pluginName
+}
+
+// Initialize implements gorm.Plugin by registering the nested set callbacks
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	p.db = db
+
+	callback := db.Callback()
+	if err := callback.Create().After("gorm:after_create").Register(callbackNameCreate, p.createCallback); err != nil {
+		return err
+	}
+
+	if err := callback.Update().After("gorm:after_update").Register(callbackNameUpdate, p.updateCallback); err != nil {
+		return err
+	}
+
+	return callback.Delete().After("gorm:after_delete").Register(callbackNameDelete, p.deleteCallback)
+}
@@ -0,0 +1,935 @@
+package nested_test
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vcraescu/gorm-nested/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+var dbName = fmt.Sprintf("test_%d.db", rand.Int())
+
+type PluginTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	plugin *nested.Plugin
+}
+
+type Taxon struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	ParentID  uint
+	Parent    *Taxon
+	TreeLeft  int `gorm-nested:"left"`
+	TreeRight int `gorm-nested:"right"`
+	TreeLevel int `gorm-nested:"level"`
+}
+
+func (t Taxon) GetParentID() interface{} {
+	return t.ParentID
+}
+
+func (t Taxon) GetParent() nested.Interface {
+	return t.Parent
+}
+
+func (suite *PluginTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		panic(fmt.Errorf("setup test: %s", err))
+	}
+
+	suite.db = db
+	suite.db.AutoMigrate(&Taxon{})
+
+	suite.plugin = nested.New()
+	if err := suite.db.Use(suite.plugin); err != nil {
+		panic(err)
+	}
+}
+
+func (suite *PluginTestSuite) TearDownTest() {
+	sqlDB, err := suite.db.DB()
+	if err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+
+	if err := os.Remove(dbName); err != nil {
+		panic(fmt.Errorf("tear down test: %s", err))
+	}
+}
+
+func (suite *PluginTestSuite) TestAddRoot() {
+	root1 := Taxon{
+		Name: "Root1",
+	}
+	suite.db.Create(&root1)
+
+	root2 := Taxon{
+		Name: "Root2",
+	}
+	suite.db.Create(&root2)
+
+	root3 := Taxon{
+		Name: "Root3",
+	}
+	suite.db.Create(&root3)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 3)
+	assert.Equal(suite.T(), 1, taxons[0].TreeLeft)
+	assert.Equal(suite.T(), 2, taxons[0].TreeRight)
+
+	assert.Equal(suite.T(), 3, taxons[1].TreeLeft)
+	assert.Equal(suite.T(), 4, taxons[1].TreeRight)
+
+	assert.Equal(suite.T(), 5, taxons[2].TreeLeft)
+	assert.Equal(suite.T(), 6, taxons[2].TreeRight)
+}
+
+func (suite *PluginTestSuite) TestInsertEntireTree() {
+	node := Taxon{
+		Name: "Tube",
+		Parent: &Taxon{
+			Name: "Television",
+			Parent: &Taxon{
+				Name: "Electronics",
+			},
+		},
+	}
+	suite.db.Create(&node)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 3)
+	assert.Equal(suite.T(), 1, taxons[0].TreeLeft)
+	assert.Equal(suite.T(), 6, taxons[0].TreeRight)
+
+	assert.Equal(suite.T(), 2, taxons[1].TreeLeft)
+	assert.Equal(suite.T(), 5, taxons[1].TreeRight)
+
+	assert.Equal(suite.T(), 3, taxons[2].TreeLeft)
+	assert.Equal(suite.T(), 4, taxons[2].TreeRight)
+}
+
+func (suite *PluginTestSuite) TestInsertNodeByNode() {
+	television := Taxon{
+		Name: "Television",
+		Parent: &Taxon{
+			Name: "Electronics",
+		},
+	}
+	suite.db.Create(&television)
+
+	tube := Taxon{
+		Name:   "Tube",
+		Parent: &television,
+	}
+	suite.db.Create(&tube)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 3)
+	assert.Equal(suite.T(), 1, taxons[0].TreeLeft)
+	assert.Equal(suite.T(), 6, taxons[0].TreeRight)
+	assert.Equal(suite.T(), 0, taxons[0].TreeLevel)
+
+	assert.Equal(suite.T(), 2, taxons[1].TreeLeft)
+	assert.Equal(suite.T(), 5, taxons[1].TreeRight)
+	assert.Equal(suite.T(), 1, taxons[1].TreeLevel)
+
+	assert.Equal(suite.T(), 3, taxons[2].TreeLeft)
+	assert.Equal(suite.T(), 4, taxons[2].TreeRight)
+	assert.Equal(suite.T(), 2, taxons[2].TreeLevel)
+}
+
+func (suite *PluginTestSuite) TestDeleteNode() {
+	suite.createTree()
+
+	television := Taxon{}
+	suite.db.First(&television, "name = 'Television'")
+	suite.db.Delete(&television)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 7)
+	var count int
+	for _, taxon := range taxons {
+		switch taxon.Name {
+		case "Electronics":
+			assert.Equal(suite.T(), 1, taxon.TreeLeft)
+			assert.Equal(suite.T(), 14, taxon.TreeRight)
+			count++
+			break
+		case "Game Consoles":
+			assert.Equal(suite.T(), 2, taxon.TreeLeft)
+			assert.Equal(suite.T(), 3, taxon.TreeRight)
+			count++
+			break
+		case "Portable Electronics":
+			assert.Equal(suite.T(), 4, taxon.TreeLeft)
+			assert.Equal(suite.T(), 13, taxon.TreeRight)
+			count++
+			break
+		case "MP3":
+			assert.Equal(suite.T(), 5, taxon.TreeLeft)
+			assert.Equal(suite.T(), 8, taxon.TreeRight)
+			count++
+			break
+		case "Flash":
+			assert.Equal(suite.T(), 6, taxon.TreeLeft)
+			assert.Equal(suite.T(), 7, taxon.TreeRight)
+			count++
+			break
+		case "CD Player":
+			assert.Equal(suite.T(), 9, taxon.TreeLeft)
+			assert.Equal(suite.T(), 10, taxon.TreeRight)
+			count++
+			break
+		case "Radio":
+			assert.Equal(suite.T(), 11, taxon.TreeLeft)
+			assert.Equal(suite.T(), 12, taxon.TreeRight)
+			count++
+			break
+		}
+	}
+
+	var portableElectronics Taxon
+	suite.db.First(&portableElectronics, "name = 'Portable Electronics'")
+	suite.db.Delete(&portableElectronics)
+
+	taxons = []Taxon{}
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 2)
+	count = 0
+	for _, taxon := range taxons {
+		switch taxon.Name {
+		case "Electronics":
+			assert.Equal(suite.T(), 1, taxon.TreeLeft)
+			assert.Equal(suite.T(), 4, taxon.TreeRight)
+			count++
+			break
+		case "Game Consoles":
+			assert.Equal(suite.T(), 2, taxon.TreeLeft)
+			assert.Equal(suite.T(), 3, taxon.TreeRight)
+			count++
+			break
+		}
+	}
+
+	var gameConsoles Taxon
+	suite.db.First(&gameConsoles, "name = 'Game Consoles'")
+	suite.db.Delete(&gameConsoles)
+	taxons = []Taxon{}
+	suite.db.Find(&taxons)
+
+	assert.Equal(suite.T(), 1, taxons[0].TreeLeft)
+	assert.Equal(suite.T(), 2, taxons[0].TreeRight)
+}
+
+func (suite *PluginTestSuite) TestMoveNodeToLeft() {
+	suite.createTree()
+
+	var portableElectronics Taxon
+	var lcd Taxon
+
+	assert.NoError(suite.T(), suite.db.First(&portableElectronics, "name = 'Portable Electronics'").Error)
+	assert.NoError(suite.T(), suite.db.First(&lcd, "name = 'LCD'").Error)
+
+	portableElectronics.Parent = &lcd
+	portableElectronics.ParentID = lcd.ID
+
+	suite.db.Save(&portableElectronics)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 11)
+
+	var count int
+	for _, taxon := range taxons {
+		switch taxon.Name {
+		case "Electronics":
+			assert.Equal(suite.T(), 1, taxon.TreeLeft)
+			assert.Equal(suite.T(), 22, taxon.TreeRight)
+			assert.Equal(suite.T(), 0, taxon.TreeLevel)
+			count++
+			break
+		case "Television":
+			assert.Equal(suite.T(), 2, taxon.TreeLeft)
+			assert.Equal(suite.T(), 19, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Game Consoles":
+			assert.Equal(suite.T(), 20, taxon.TreeLeft)
+			assert.Equal(suite.T(), 21, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Tube":
+			assert.Equal(suite.T(), 3, taxon.TreeLeft)
+			assert.Equal(suite.T(), 4, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "LCD":
+			assert.Equal(suite.T(), 5, taxon.TreeLeft)
+			assert.Equal(suite.T(), 16, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Plasma":
+			assert.Equal(suite.T(), 17, taxon.TreeLeft)
+			assert.Equal(suite.T(), 18, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Portable Electronics":
+			assert.Equal(suite.T(), 6, taxon.TreeLeft)
+			assert.Equal(suite.T(), 15, taxon.TreeRight)
+			assert.Equal(suite.T(), 3, taxon.TreeLevel)
+			count++
+			break
+		case "MP3":
+			assert.Equal(suite.T(), 7, taxon.TreeLeft)
+			assert.Equal(suite.T(), 10, taxon.TreeRight)
+			assert.Equal(suite.T(), 4, taxon.TreeLevel)
+			count++
+			break
+		case "Flash":
+			assert.Equal(suite.T(), 8, taxon.TreeLeft)
+			assert.Equal(suite.T(), 9, taxon.TreeRight)
+			assert.Equal(suite.T(), 5, taxon.TreeLevel)
+			count++
+			break
+		case "CD Player":
+			assert.Equal(suite.T(), 11, taxon.TreeLeft)
+			assert.Equal(suite.T(), 12, taxon.TreeRight)
+			assert.Equal(suite.T(), 4, taxon.TreeLevel)
+			count++
+			break
+		case "Radio":
+			assert.Equal(suite.T(), 13, taxon.TreeLeft)
+			assert.Equal(suite.T(), 14, taxon.TreeRight)
+			assert.Equal(suite.T(), 4, taxon.TreeLevel)
+			count++
+			break
+		}
+	}
+
+	assert.Equal(suite.T(), len(taxons), count)
+}
+
+func (suite *PluginTestSuite) TestMoveNodeToRight() {
+	suite.createTree()
+
+	var mp3 Taxon
+	var lcd Taxon
+
+	assert.NoError(suite.T(), suite.db.First(&mp3, "name = 'MP3'").Error)
+	assert.NoError(suite.T(), suite.db.First(&lcd, "name = 'LCD'").Error)
+
+	lcd.Parent = &mp3
+	lcd.ParentID = mp3.ID
+
+	suite.db.Save(&lcd)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 11)
+
+	var count int
+	for _, taxon := range taxons {
+		switch taxon.Name {
+		case "Electronics":
+			assert.Equal(suite.T(), 1, taxon.TreeLeft)
+			assert.Equal(suite.T(), 22, taxon.TreeRight)
+			assert.Equal(suite.T(), 0, taxon.TreeLevel)
+			count++
+			break
+		case "Television":
+			assert.Equal(suite.T(), 2, taxon.TreeLeft)
+			assert.Equal(suite.T(), 7, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Game Consoles":
+			assert.Equal(suite.T(), 8, taxon.TreeLeft)
+			assert.Equal(suite.T(), 9, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Tube":
+			assert.Equal(suite.T(), 3, taxon.TreeLeft)
+			assert.Equal(suite.T(), 4, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Plasma":
+			assert.Equal(suite.T(), 5, taxon.TreeLeft)
+			assert.Equal(suite.T(), 6, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Portable Electronics":
+			assert.Equal(suite.T(), 10, taxon.TreeLeft)
+			assert.Equal(suite.T(), 21, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "MP3":
+			assert.Equal(suite.T(), 11, taxon.TreeLeft)
+			assert.Equal(suite.T(), 16, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Flash":
+			assert.Equal(suite.T(), 12, taxon.TreeLeft)
+			assert.Equal(suite.T(), 13, taxon.TreeRight)
+			assert.Equal(suite.T(), 3, taxon.TreeLevel)
+			count++
+			break
+		case "LCD":
+			assert.Equal(suite.T(), 14, taxon.TreeLeft)
+			assert.Equal(suite.T(), 15, taxon.TreeRight)
+			assert.Equal(suite.T(), 3, taxon.TreeLevel)
+			count++
+			break
+		case "CD Player":
+			assert.Equal(suite.T(), 17, taxon.TreeLeft)
+			assert.Equal(suite.T(), 18, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Radio":
+			assert.Equal(suite.T(), 19, taxon.TreeLeft)
+			assert.Equal(suite.T(), 20, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		}
+	}
+
+	assert.Equal(suite.T(), len(taxons), count)
+}
+
+func (suite *PluginTestSuite) TestChildNodeBecomesRoot() {
+	suite.createTree()
+
+	var mp3 Taxon
+
+	assert.NoError(suite.T(), suite.db.First(&mp3, "name = 'MP3'").Error)
+
+	mp3.Parent = nil
+	mp3.ParentID = 0
+
+	suite.db.Save(&mp3)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 11)
+
+	var count int
+	for _, taxon := range taxons {
+		switch taxon.Name {
+		case "Electronics":
+			assert.Equal(suite.T(), 1, taxon.TreeLeft)
+			assert.Equal(suite.T(), 18, taxon.TreeRight)
+			assert.Equal(suite.T(), 0, taxon.TreeLevel)
+			count++
+			break
+		case "Television":
+			assert.Equal(suite.T(), 2, taxon.TreeLeft)
+			assert.Equal(suite.T(), 9, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Game Consoles":
+			assert.Equal(suite.T(), 10, taxon.TreeLeft)
+			assert.Equal(suite.T(), 11, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Tube":
+			assert.Equal(suite.T(), 3, taxon.TreeLeft)
+			assert.Equal(suite.T(), 4, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "LCD":
+			assert.Equal(suite.T(), 5, taxon.TreeLeft)
+			assert.Equal(suite.T(), 6, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Plasma":
+			assert.Equal(suite.T(), 7, taxon.TreeLeft)
+			assert.Equal(suite.T(), 8, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Portable Electronics":
+			assert.Equal(suite.T(), 12, taxon.TreeLeft)
+			assert.Equal(suite.T(), 17, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "CD Player":
+			assert.Equal(suite.T(), 13, taxon.TreeLeft)
+			assert.Equal(suite.T(), 14, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Radio":
+			assert.Equal(suite.T(), 15, taxon.TreeLeft)
+			assert.Equal(suite.T(), 16, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "MP3":
+			assert.Equal(suite.T(), 19, taxon.TreeLeft)
+			assert.Equal(suite.T(), 22, taxon.TreeRight)
+			assert.Equal(suite.T(), 0, taxon.TreeLevel)
+			count++
+			break
+		case "Flash":
+			assert.Equal(suite.T(), 20, taxon.TreeLeft)
+			assert.Equal(suite.T(), 21, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		}
+	}
+
+	assert.Equal(suite.T(), len(taxons), count)
+}
+
+func (suite *PluginTestSuite) TestAutoUpdateParentAssociation() {
+	electronics := Taxon{
+		Name: "Electronics",
+	}
+
+	television := Taxon{
+		Name:   "Television",
+		Parent: &electronics,
+	}
+	gameConsoles := Taxon{
+		Name:   "Game Consoles",
+		Parent: &electronics,
+	}
+	portableElectronics := Taxon{
+		Name:   "Portable Electronics",
+		Parent: &electronics,
+	}
+
+	tube := Taxon{
+		Name:   "Tube",
+		Parent: &television,
+	}
+	lcd := Taxon{
+		Name:   "LCD",
+		Parent: &television,
+	}
+	plasma := Taxon{
+		Name:   "Plasma",
+		Parent: &television,
+	}
+
+	mp3 := Taxon{
+		Name:   "MP3",
+		Parent: &portableElectronics,
+	}
+
+	cdPlayer := Taxon{
+		Name:   "CD Player",
+		Parent: &portableElectronics,
+	}
+
+	radio := Taxon{
+		Name:   "Radio",
+		Parent: &portableElectronics,
+	}
+
+	flash := Taxon{
+		Name:   "Flash",
+		Parent: &mp3,
+	}
+
+	suite.db.Save(&television)
+	suite.db.Save(&gameConsoles)
+	suite.db.Save(&tube)
+	suite.db.Save(&lcd)
+	suite.db.Save(&plasma)
+	suite.db.Save(&flash)
+	suite.db.Save(&cdPlayer)
+	suite.db.Save(&radio)
+
+	assert.Equal(suite.T(), 1, electronics.TreeLeft)
+	assert.Equal(suite.T(), 22, electronics.TreeRight)
+	assert.Equal(suite.T(), 0, electronics.TreeLevel)
+
+	assert.Equal(suite.T(), 2, television.TreeLeft)
+	assert.Equal(suite.T(), 9, television.TreeRight)
+	assert.Equal(suite.T(), 1, television.TreeLevel)
+
+	assert.Equal(suite.T(), 3, tube.TreeLeft)
+	assert.Equal(suite.T(), 4, tube.TreeRight)
+	assert.Equal(suite.T(), 2, tube.TreeLevel)
+
+	assert.Equal(suite.T(), 5, lcd.TreeLeft)
+	assert.Equal(suite.T(), 6, lcd.TreeRight)
+	assert.Equal(suite.T(), 2, lcd.TreeLevel)
+
+	assert.Equal(suite.T(), 7, plasma.TreeLeft)
+	assert.Equal(suite.T(), 8, plasma.TreeRight)
+	assert.Equal(suite.T(), 2, plasma.TreeLevel)
+
+	//assert.Equal(suite.T(), 10, gameConsoles.TreeLeft)
+	//assert.Equal(suite.T(), 11, gameConsoles.TreeRight)
+	//assert.Equal(suite.T(), 1, gameConsoles.TreeLevel)
+
+	assert.Equal(suite.T(), 12, portableElectronics.TreeLeft)
+	assert.Equal(suite.T(), 21, portableElectronics.TreeRight)
+	assert.Equal(suite.T(), 1, portableElectronics.TreeLevel)
+
+	assert.Equal(suite.T(), 13, mp3.TreeLeft)
+	assert.Equal(suite.T(), 16, mp3.TreeRight)
+	assert.Equal(suite.T(), 2, mp3.TreeLevel)
+
+	assert.Equal(suite.T(), 17, cdPlayer.TreeLeft)
+	assert.Equal(suite.T(), 18, cdPlayer.TreeRight)
+	assert.Equal(suite.T(), 2, cdPlayer.TreeLevel)
+
+	assert.Equal(suite.T(), 19, radio.TreeLeft)
+	assert.Equal(suite.T(), 20, radio.TreeRight)
+	assert.Equal(suite.T(), 2, radio.TreeLevel)
+
+	assert.Equal(suite.T(), 14, flash.TreeLeft)
+	assert.Equal(suite.T(), 15, flash.TreeRight)
+	assert.Equal(suite.T(), 3, flash.TreeLevel)
+}
+
+func (suite *PluginTestSuite) createTree() {
+	electronics := Taxon{
+		Name: "Electronics",
+	}
+
+	television := Taxon{
+		Name:   "Television",
+		Parent: &electronics,
+	}
+	gameConsoles := Taxon{
+		Name:   "Game Consoles",
+		Parent: &electronics,
+	}
+	portableElectronics := Taxon{
+		Name:   "Portable Electronics",
+		Parent: &electronics,
+	}
+
+	tube := Taxon{
+		Name:   "Tube",
+		Parent: &television,
+	}
+	lcd := Taxon{
+		Name:   "LCD",
+		Parent: &television,
+	}
+	plasma := Taxon{
+		Name:   "Plasma",
+		Parent: &television,
+	}
+
+	mp3 := Taxon{
+		Name:   "MP3",
+		Parent: &portableElectronics,
+	}
+
+	cdPlayer := Taxon{
+		Name:   "CD Player",
+		Parent: &portableElectronics,
+	}
+
+	radio := Taxon{
+		Name:   "Radio",
+		Parent: &portableElectronics,
+	}
+
+	flash := Taxon{
+		Name:   "Flash",
+		Parent: &mp3,
+	}
+
+	suite.db.Save(&television)
+	suite.db.Save(&gameConsoles)
+	suite.db.Save(&tube)
+	suite.db.Save(&lcd)
+	suite.db.Save(&plasma)
+	suite.db.Save(&flash)
+	suite.db.Save(&cdPlayer)
+	suite.db.Save(&radio)
+
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	assert.Len(suite.T(), taxons, 11)
+	var count int
+	for _, taxon := range taxons {
+		switch taxon.Name {
+		case "Electronics":
+			assert.Equal(suite.T(), 1, taxon.TreeLeft)
+			assert.Equal(suite.T(), 22, taxon.TreeRight)
+			assert.Equal(suite.T(), 0, taxon.TreeLevel)
+			count++
+			break
+		case "Television":
+			assert.Equal(suite.T(), 2, taxon.TreeLeft)
+			assert.Equal(suite.T(), 9, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Tube":
+			assert.Equal(suite.T(), 3, taxon.TreeLeft)
+			assert.Equal(suite.T(), 4, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+		case "LCD":
+			assert.Equal(suite.T(), 5, taxon.TreeLeft)
+			assert.Equal(suite.T(), 6, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Plasma":
+			assert.Equal(suite.T(), 7, taxon.TreeLeft)
+			assert.Equal(suite.T(), 8, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Game Consoles":
+			assert.Equal(suite.T(), 10, taxon.TreeLeft)
+			assert.Equal(suite.T(), 11, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "Portable Electronics":
+			assert.Equal(suite.T(), 12, taxon.TreeLeft)
+			assert.Equal(suite.T(), 21, taxon.TreeRight)
+			assert.Equal(suite.T(), 1, taxon.TreeLevel)
+			count++
+			break
+		case "MP3":
+			assert.Equal(suite.T(), 13, taxon.TreeLeft)
+			assert.Equal(suite.T(), 16, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "CD Player":
+			assert.Equal(suite.T(), 17, taxon.TreeLeft)
+			assert.Equal(suite.T(), 18, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Radio":
+			assert.Equal(suite.T(), 19, taxon.TreeLeft)
+			assert.Equal(suite.T(), 20, taxon.TreeRight)
+			assert.Equal(suite.T(), 2, taxon.TreeLevel)
+			count++
+			break
+		case "Flash":
+			assert.Equal(suite.T(), 14, taxon.TreeLeft)
+			assert.Equal(suite.T(), 15, taxon.TreeRight)
+			assert.Equal(suite.T(), 3, taxon.TreeLevel)
+			count++
+			break
+		}
+	}
+
+	assert.Equal(suite.T(), len(taxons), count)
+}
+
+func (suite *PluginTestSuite) TestDescendants() {
+	suite.createTree()
+
+	var portableElectronics Taxon
+	suite.db.First(&portableElectronics, "name = 'Portable Electronics'")
+
+	var taxons []Taxon
+	assert.NoError(suite.T(), suite.plugin.Descendants(&portableElectronics, &taxons))
+
+	var names []string
+	for _, taxon := range taxons {
+		names = append(names, taxon.Name)
+	}
+
+	assert.Equal(suite.T(), []string{"MP3", "Flash", "CD Player", "Radio"}, names)
+}
+
+func (suite *PluginTestSuite) TestAncestors() {
+	suite.createTree()
+
+	var flash Taxon
+	suite.db.First(&flash, "name = 'Flash'")
+
+	var taxons []*Taxon
+	assert.NoError(suite.T(), suite.plugin.Ancestors(&flash, &taxons))
+
+	var names []string
+	for _, taxon := range taxons {
+		names = append(names, taxon.Name)
+	}
+
+	assert.Equal(suite.T(), []string{"Electronics", "Portable Electronics", "MP3"}, names)
+}
+
+func (suite *PluginTestSuite) TestMove() {
+	suite.createTree()
+
+	var flash, television Taxon
+	suite.db.First(&flash, "name = 'Flash'")
+	suite.db.First(&television, "name = 'Television'")
+
+	assert.NoError(suite.T(), suite.plugin.Move(&flash, &television))
+	assert.Equal(suite.T(), television.ID, flash.ParentID)
+	assert.Equal(suite.T(), 9, flash.TreeLeft)
+	assert.Equal(suite.T(), 10, flash.TreeRight)
+	assert.Equal(suite.T(), 2, flash.TreeLevel)
+
+	assert.NoError(suite.T(), suite.plugin.Move(&flash, nil))
+	assert.Equal(suite.T(), uint(0), flash.ParentID)
+	assert.Equal(suite.T(), 21, flash.TreeLeft)
+	assert.Equal(suite.T(), 22, flash.TreeRight)
+	assert.Equal(suite.T(), 0, flash.TreeLevel)
+}
+
+func (suite *PluginTestSuite) TestMoveWithStaleNodes() {
+	suite.createTree()
+
+	// the values of television and portable electronics in memory are outdated by the first move
+	var lcd, television, portableElectronics Taxon
+	suite.db.First(&lcd, "name = 'LCD'")
+	suite.db.First(&television, "name = 'Television'")
+	suite.db.First(&portableElectronics, "name = 'Portable Electronics'")
+
+	assert.NoError(suite.T(), suite.plugin.Move(&lcd, &portableElectronics))
+	assert.NoError(suite.T(), suite.plugin.Move(&lcd, &television))
+	assert.NoError(suite.T(), suite.plugin.Move(&portableElectronics, &television))
+
+	var taxons []Taxon
+	assert.NoError(suite.T(), suite.plugin.Descendants(&television, &taxons))
+
+	var names []string
+	for _, taxon := range taxons {
+		names = append(names, taxon.Name)
+	}
+
+	assert.Equal(
+		suite.T(),
+		[]string{"Tube", "Plasma", "LCD", "Portable Electronics", "MP3", "Flash", "CD Player", "Radio"},
+		names,
+	)
+	suite.assertNumbering()
+}
+
+func (suite *PluginTestSuite) TestMoveUnderOwnSubtree() {
+	suite.createTree()
+
+	var electronics, portableElectronics, flash Taxon
+	suite.db.First(&electronics, "name = 'Electronics'")
+	suite.db.First(&portableElectronics, "name = 'Portable Electronics'")
+	suite.db.First(&flash, "name = 'Flash'")
+
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.Move(&portableElectronics, &flash),
+		fmt.Sprintf("move: %d cannot be moved under its descendant %d", portableElectronics.ID, flash.ID),
+	)
+	assert.EqualError(
+		suite.T(),
+		suite.plugin.Move(&portableElectronics, &portableElectronics),
+		fmt.Sprintf("move: %d cannot be moved under itself", portableElectronics.ID),
+	)
+	assert.Error(suite.T(), suite.plugin.Move(&electronics, &flash))
+
+	suite.db.First(&portableElectronics, portableElectronics.ID)
+	assert.Equal(suite.T(), electronics.ID, portableElectronics.ParentID)
+	assert.Equal(suite.T(), 12, portableElectronics.TreeLeft)
+	assert.Equal(suite.T(), 21, portableElectronics.TreeRight)
+	suite.assertNumbering()
+}
+
+// assertNumbering asserts that the left/right values number the tree of the parent
+// foreign keys from 1, every value being used once
+func (suite *PluginTestSuite) assertNumbering() {
+	var taxons []Taxon
+	suite.db.Find(&taxons)
+
+	byID := map[uint]Taxon{}
+	used := map[int]int{}
+	for _, taxon := range taxons {
+		byID[taxon.ID] = taxon
+		used[taxon.TreeLeft]++
+		used[taxon.TreeRight]++
+	}
+
+	for value := 1; value <= 2*len(taxons); value++ {
+		assert.Equal(suite.T(), 1, used[value], "value %d", value)
+	}
+
+	for _, taxon := range taxons {
+		if taxon.ParentID == 0 {
+			assert.Equal(suite.T(), 0, taxon.TreeLevel, taxon.Name)
+			continue
+		}
+
+		parent := byID[taxon.ParentID]
+		assert.True(suite.T(), parent.TreeLeft < taxon.TreeLeft && taxon.TreeRight < parent.TreeRight, taxon.Name)
+		assert.Equal(suite.T(), parent.TreeLevel+1, taxon.TreeLevel, taxon.Name)
+	}
+}
+
+func (suite *PluginTestSuite) TestGetTreeLeft() {
+	t := &Taxon{
+		TreeLeft: 41,
+	}
+
+	assert.Equal(suite.T(), 41, nested.GetTreeLeft(t))
+}
+
+func (suite *PluginTestSuite) TestGetTreeRight() {
+	t := &Taxon{
+		TreeRight: 41,
+	}
+
+	assert.Equal(suite.T(), 41, nested.GetTreeRight(t))
+}
+
+func (suite *PluginTestSuite) TestGetTreeLevel() {
+	t := &Taxon{
+		TreeLevel: 41,
+	}
+
+	assert.Equal(suite.T(), 41, nested.GetTreeLevel(t))
+}
+
+func TestPluginTestSuite(t *testing.T) {
+	suite.Run(t, new(PluginTestSuite))
+}
@@ -0,0 +1,126 @@
+package nested
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// Descendants finds all the descendants of the node ordered by their position in the tree
+func (p *Plugin) Descendants(node Interface, out interface{}) error {
+	t, err := p.tree(node)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(node))
+
+	return p.db.
+		Where(t.expr(":tree_left > ? AND :tree_right < ?"), t.int(t.left, rv), t.int(t.right, rv)).
+		Order(t.expr(":tree_left")).
+		Find(out).
+		Error
+}
+
+// Ancestors finds all the ancestors of the node starting with the root
+func (p *Plugin) Ancestors(node Interface, out interface{}) error {
+	t, err := p.tree(node)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(node))
+
+	return p.db.
+		Where(t.expr(":tree_left < ? AND :tree_right > ?"), t.int(t.left, rv), t.int(t.right, rv)).
+		Order(t.expr(":tree_level")).
+		Find(out).
+		Error
+}
+
+// Move moves the node subtree to the end of the children of parent or to the end
+// of the roots when parent is nil. The tree values of both nodes are reloaded first,
+// in the transaction of the move, and parent cannot be the node or one of its
+// descendants.
+func (p *Plugin) Move(node, parent Interface) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		t, err := treeOf(tx, node)
+		if err != nil {
+			return err
+		}
+
+		rv := reflect.Indirect(reflect.ValueOf(node))
+		if err := t.reloadTreeValues(rv); err != nil {
+			return err
+		}
+
+		if !isNilInterface(parent) {
+			prv := reflect.Indirect(reflect.ValueOf(parent))
+			if err := t.reloadTreeValues(prv); err != nil {
+				return err
+			}
+
+			if err := t.checkNotInSubtree(rv, prv); err != nil {
+				return err
+			}
+		}
+
+		if err := setParent(tx, node, parent); err != nil {
+			return err
+		}
+
+		return tx.Save(node).Error
+	})
+}
+
+// reloadTreeValues sets the tree values of the node to the stored ones, leaving the other
+// fields as they are so that they are saved along with the move
+func (t *tree) reloadTreeValues(rv reflect.Value) error {
+	stored := reflect.New(t.schema.ModelType)
+	if err := t.db.Where(t.pkCondition(), t.id(rv)).Take(stored.Interface()).Error; err != nil {
+		return err
+	}
+
+	for _, f := range []*schema.Field{t.left, t.right, t.level} {
+		v, _ := f.ValueOf(t.ctx, stored.Elem())
+		if err := f.Set(t.ctx, rv, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkNotInSubtree fails when parent is the node or one of its descendants, which would
+// detach the subtree from the tree
+func (t *tree) checkNotInSubtree(rv, parent reflect.Value) error {
+	if t.isSameNode(rv, parent) {
+		return fmt.Errorf("move: %v cannot be moved under itself", t.id(rv))
+	}
+
+	if t.int(t.left, parent) > t.int(t.left, rv) && t.int(t.right, parent) < t.int(t.right, rv) {
+		return fmt.Errorf("move: %v cannot be moved under its descendant %v", t.id(rv), t.id(parent))
+	}
+
+	return nil
+}
+
+func (p *Plugin) tree(node Interface) (*tree, error) {
+	return treeOf(p.db, node)
+}
+
+// treeOf returns the tree of the model of node running its statements on db
+func treeOf(db *gorm.DB, node Interface) (*tree, error) {
+	s, err := parseSchema(db, node)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := newTree(db, s)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a tree node", node)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,115 @@
+package nested
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
+)
+
+var schemaCache = &sync.Map{}
+
+func isZeroValue(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+func isNilInterface(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// parseSchema returns the schema of the model of db or of node when db is nil
+func parseSchema(db *gorm.DB, node interface{}) (*schema.Schema, error) {
+	if db != nil {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(node); err != nil {
+			return nil, err
+		}
+
+		return stmt.Schema, nil
+	}
+
+	return schema.Parse(node, schemaCache, schema.NamingStrategy{})
+}
+
+// parentRelationship returns the belongs to association of the model with itself
+func parentRelationship(s *schema.Schema) (*schema.Relationship, bool) {
+	for _, rel := range s.Relationships.BelongsTo {
+		if rel.FieldSchema != nil && rel.FieldSchema.ModelType == s.ModelType && len(rel.References) > 0 {
+			return rel, true
+		}
+	}
+
+	return nil, false
+}
+
+// setParent sets the parent association and the parent foreign key of the node
+func setParent(db *gorm.DB, node, parent Interface) error {
+	s, err := parseSchema(db, node)
+	if err != nil {
+		return err
+	}
+
+	rel, ok := parentRelationship(s)
+	if !ok {
+		return fmt.Errorf("%T has no parent association", node)
+	}
+
+	ctx := db.Statement.Context
+	rv := reflect.Indirect(reflect.ValueOf(node))
+	fk := rel.References[0].ForeignKey
+	if isNilInterface(parent) {
+		if err := rel.Field.Set(ctx, rv, reflect.Zero(rel.Field.FieldType).Interface()); err != nil {
+			return err
+		}
+
+		return fk.Set(ctx, rv, reflect.Zero(fk.FieldType).Interface())
+	}
+
+	if err := rel.Field.Set(ctx, rv, parent); err != nil {
+		return err
+	}
+
+	id, _ := rel.References[0].PrimaryKey.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(parent)))
+
+	return fk.Set(ctx, rv, id)
+}
+
+func getTreeLeft(node Interface) int {
+	return getTagInt(node, "left")
+}
+
+func getTreeRight(node Interface) int {
+	return getTagInt(node, "right")
+}
+
+func getTreeLevel(node Interface) int {
+	return getTagInt(node, "level")
+}
+
+func getTagInt(node Interface, tagValue string) int {
+	s, err := parseSchema(nil, node)
+	if err != nil {
+		return 0
+	}
+
+	f := fieldByTagValue(s, tagValue)
+	if f == nil {
+		return 0
+	}
+
+	v, _ := f.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(node)))
+
+	return int(reflect.ValueOf(v).Int())
+}